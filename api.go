@@ -0,0 +1,38 @@
+package bbb
+
+import (
+	"context"
+	"io"
+)
+
+// API is implemented by BigBlueButton and covers every action of the
+// BigBlueButton API, so that clients can be wrapped (caching, retries,
+// metrics), spread over several servers or replaced by fakes. Only the
+// context variants are part of it; the plain methods of BigBlueButton are
+// shorthands using context.Background.
+//
+// The bbb-webhooks API is not included, see BigBlueButton.Hooks.
+type API interface {
+	CreateWithContext(ctx context.Context, id string, options OptionEncoder) (*Meeting, error)
+	JoinURL(name, meetingID, password string, options OptionEncoder) string
+	IsMeetingRunningWithContext(ctx context.Context, id string) (bool, error)
+	EndWithContext(ctx context.Context, id, password string, options *EndOptions) error
+	MeetingInfoWithContext(ctx context.Context, id, password string) (*Meeting, error)
+	MeetingsWithContext(ctx context.Context) ([]*Meeting, error)
+	BreakoutRoomsWithContext(ctx context.Context, parent *Meeting) ([]*Meeting, error)
+	InsertDocumentWithContext(ctx context.Context, meetingID string, docs []ConfigXML_Document) error
+
+	RecordingsWithContext(ctx context.Context, meetings []string) ([]*Recording, error)
+	RecordingsPageWithContext(ctx context.Context, options *RecordingsOptions) (*RecordingPage, error)
+	PublishRecordingsWithContext(ctx context.Context, recordings []string, publish bool) (bool, error)
+	DeleteRecordingsWithContext(ctx context.Context, recordings []string) (bool, error)
+	UpdateRecordingsWithContext(ctx context.Context, recordings []string, meta map[string]string) (map[string]error, error)
+	RecordingTextTracksWithContext(ctx context.Context, recordID string) ([]TextTrack, error)
+	PutRecordingTextTrackWithContext(ctx context.Context, recordID string, options *TextTrackOptions, track io.Reader) error
+
+	DefaultConfigXMLWithContext(ctx context.Context) (*ConfigXML, error)
+	SetConfigXMLWithContext(ctx context.Context, meeting string, c *ConfigXML) (string, error)
+	ServerVersionWithContext(ctx context.Context) (string, error)
+}
+
+var _ API = (*BigBlueButton)(nil)
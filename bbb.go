@@ -2,9 +2,10 @@ package bbb
 
 import (
 	"bytes"
-	"crypto/sha1"
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -22,43 +23,76 @@ func New(apiurl, secret string) (BigBlueButton, error) {
 }
 
 type BigBlueButton struct {
-	Secret string
-	Url    *url.URL
+	Secret   string
+	Url      *url.URL
+	Checksum ChecksumAlgorithm
+
+	// Client is used for all API requests; http.DefaultClient if nil.
+	Client *http.Client
+
+	// MaxDocumentSize limits the size of every inline document, before
+	// encoding; DefaultMaxDocumentSize if 0.
+	MaxDocumentSize int64
 }
 
 func (b3 *BigBlueButton) Create(id string, options OptionEncoder) (*Meeting, error) {
+	return b3.CreateWithContext(context.Background(), id, options)
+}
+
+func (b3 *BigBlueButton) CreateWithContext(ctx context.Context, id string, options OptionEncoder) (*Meeting, error) {
 	u := b3.makeURL("create", mergeUrlValues(url.Values{"meetingID": {id}}, options.Values()))
 	var (
 		res *http.Response
 		err error
 	)
 	if options, ok := options.(*CreateOptions); ok && len(options.Documents) > 0 {
-		if mods, oops := buildCreateMeetingXML(options.Documents); nil == oops {
-			res, err = http.Post(u.String(), "text/xml", bytes.NewReader(mods))
-		} else {
-			err = oops
+		var body io.ReadCloser
+		if body, err = b3.documentsBody(options.Documents); nil == err {
+			res, err = b3.post(ctx, u.String(), "text/xml", body)
+			body.Close()
 		}
 	} else {
-		res, err = http.Get(u.String())
+		res, err = b3.get(ctx, u.String())
 	}
 	if nil != err {
 		return nil, err
 	}
-	defer res.Body.Close()
+	defer closeResponse(res)
 	return loadMeetingCreateResponse(res)
 }
 
 func (b3 *BigBlueButton) DefaultConfigXML() (*ConfigXML, error) {
-	u := b3.makeURL("getDefaultConfigXML", url.Values{})
-	res, err := http.Get(u.String())
+	return b3.DefaultConfigXMLWithContext(context.Background())
+}
+
+func (b3 *BigBlueButton) DefaultConfigXMLWithContext(ctx context.Context) (*ConfigXML, error) {
+	action := "getDefaultConfigXML"
+	u := b3.makeURL(action, url.Values{})
+	res, err := b3.get(ctx, u.String())
 	if nil != err {
 		return nil, err
 	}
-	defer res.Body.Close()
-	return readConfigXML(res.Body)
+	defer closeResponse(res)
+	data, err := ioutil.ReadAll(res.Body)
+	if nil != err {
+		return nil, err
+	}
+	if res.StatusCode >= 300 || bytes.Contains(data, []byte("<response>")) {
+		r := *res
+		r.Body = ioutil.NopCloser(bytes.NewReader(data))
+		if err := loadResponse(&r, action, &responseXML{}); nil != err {
+			return nil, err
+		}
+		return nil, &APIError{Action: action, Message: "invalid response", StatusCode: res.StatusCode}
+	}
+	return readConfigXML(bytes.NewReader(data))
 }
 
 func (b3 *BigBlueButton) SetConfigXML(meeting string, c *ConfigXML) (string, error) {
+	return b3.SetConfigXMLWithContext(context.Background(), meeting, c)
+}
+
+func (b3 *BigBlueButton) SetConfigXMLWithContext(ctx context.Context, meeting string, c *ConfigXML) (string, error) {
 	action := "setConfigXML"
 	params := url.Values{
 		"meetingID": {meeting},
@@ -69,130 +103,290 @@ func (b3 *BigBlueButton) SetConfigXML(meeting string, c *ConfigXML) (string, err
 	if nil != err {
 		return "", err
 	}
-	res, err := http.PostForm(u.String(), params)
+	res, err := b3.post(ctx, u.String(), "application/x-www-form-urlencoded",
+		strings.NewReader(params.Encode()))
 	if nil != err {
 		return "", err
 	}
-	defer res.Body.Close()
-	return loadStringResponse(res, "configToken"), nil
+	defer closeResponse(res)
+	var response configTokenResponse
+	if err := loadResponse(res, action, &response); nil != err {
+		return "", err
+	}
+	return response.ConfigToken, nil
 }
 
+// JoinURL returns the URL to join a meeting. The password may be empty if
+// the options carry a role instead.
 func (b3 *BigBlueButton) JoinURL(name, meetingID, password string, options OptionEncoder) string {
-	return b3.makeURL("join", mergeUrlValues(
-		url.Values{
-			"fullName":  {name},
-			"meetingID": {meetingID},
-			"password":  {password},
-		},
-		options.Values())).String()
+	params := url.Values{
+		"fullName":  {name},
+		"meetingID": {meetingID},
+	}
+	if "" != password {
+		params.Set("password", password)
+	}
+	return b3.makeURL("join", mergeUrlValues(params, options.Values())).String()
 }
 
-func (b3 *BigBlueButton) IsMeetingRunning(id string) bool {
-	u := b3.makeURL("isMeetingRunning", url.Values{"meetingID": {id}})
-	res, err := http.Get(u.String())
+func (b3 *BigBlueButton) IsMeetingRunning(id string) (bool, error) {
+	return b3.IsMeetingRunningWithContext(context.Background(), id)
+}
+
+func (b3 *BigBlueButton) IsMeetingRunningWithContext(ctx context.Context, id string) (bool, error) {
+	action := "isMeetingRunning"
+	u := b3.makeURL(action, url.Values{"meetingID": {id}})
+	res, err := b3.get(ctx, u.String())
 	if nil != err {
-		return false
+		return false, err
+	}
+	defer closeResponse(res)
+	var response runningResponse
+	if err := loadResponse(res, action, &response); nil != err {
+		return false, err
 	}
-	defer res.Body.Close()
-	return loadBoolResponse(res, "running")
+	return bool(response.Running), nil
 }
 
-func (b3 *BigBlueButton) End(id, password string) bool {
-	u := b3.makeURL("end", url.Values{"meetingID": {id}, "password": {password}})
-	if _, err := http.Get(u.String()); nil == err {
-		for retries := 0; retries < 10; retries++ {
-			if _, err := b3.MeetingInfo(id, password); nil != err {
-				return true
+// End ends the meeting and, depending on options, waits until the server
+// confirms it; nil options do not wait. If the meeting did not exist, or
+// was already ended, IsNotFound reports true for the returned error.
+func (b3 *BigBlueButton) End(id, password string, options *EndOptions) error {
+	return b3.EndWithContext(context.Background(), id, password, options)
+}
+
+func (b3 *BigBlueButton) EndWithContext(ctx context.Context, id, password string, options *EndOptions) error {
+	action := "end"
+	u := b3.makeURL(action, url.Values{"meetingID": {id}, "password": {password}})
+	res, err := b3.get(ctx, u.String())
+	if nil != err {
+		return err
+	}
+	err = loadResponse(res, action, &responseXML{})
+	closeResponse(res)
+	if nil != err || nil == options || EndNoWait == options.Wait {
+		return err
+	}
+	if _, t := ctx.Deadline(); !t {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.maxWait())
+		defer cancel()
+	}
+	for interval := options.interval(); ; interval = options.next(interval) {
+		var done bool
+		switch options.Wait {
+		case EndWaitGone:
+			if _, err = b3.MeetingInfoWithContext(ctx, id, password); IsNotFound(err) {
+				done, err = true, nil
 			}
-			time.Sleep(2 * time.Second)
+		case EndWaitNotRunning:
+			var running bool
+			running, err = b3.IsMeetingRunningWithContext(ctx, id)
+			done = nil == err && !running
+		default:
+			return fmt.Errorf("bbb: unknown end strategy %d", options.Wait)
+		}
+		if done {
+			return nil
+		}
+		if nil != ctx.Err() {
+			return ctx.Err()
+		}
+		if nil != err && !transient(err) {
+			return err
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
-	return false
 }
 
 func (b3 *BigBlueButton) MeetingInfo(id, password string) (*Meeting, error) {
+	return b3.MeetingInfoWithContext(context.Background(), id, password)
+}
+
+func (b3 *BigBlueButton) MeetingInfoWithContext(ctx context.Context, id, password string) (*Meeting, error) {
 	u := b3.makeURL("getMeetingInfo", url.Values{"meetingID": {id}, "password": {password}})
-	res, err := http.Get(u.String())
+	res, err := b3.get(ctx, u.String())
 	if nil != err {
 		return nil, err
 	}
-	defer res.Body.Close()
+	defer closeResponse(res)
 	return loadMeetingInfoResponse(res)
 }
 
-func (b3 *BigBlueButton) Meetings() []*Meeting {
+func (b3 *BigBlueButton) Meetings() ([]*Meeting, error) {
+	return b3.MeetingsWithContext(context.Background())
+}
+
+func (b3 *BigBlueButton) MeetingsWithContext(ctx context.Context) ([]*Meeting, error) {
 	u := b3.makeURL("getMeetings", url.Values{})
-	res, err := http.Get(u.String())
+	res, err := b3.get(ctx, u.String())
 	if nil != err {
-		return []*Meeting{}
+		return nil, err
 	}
-	defer res.Body.Close()
+	defer closeResponse(res)
 	return loadMeetigsResponse(res)
 }
 
-func (b3 *BigBlueButton) Recordings(meetings []string) []*Recording {
+// BreakoutRooms returns the running breakout rooms of parent, ordered by
+// sequence number.
+func (b3 *BigBlueButton) BreakoutRooms(parent *Meeting) ([]*Meeting, error) {
+	return b3.BreakoutRoomsWithContext(context.Background(), parent)
+}
+
+func (b3 *BigBlueButton) BreakoutRoomsWithContext(ctx context.Context, parent *Meeting) ([]*Meeting, error) {
+	meetings, err := b3.MeetingsWithContext(ctx)
+	if nil != err {
+		return nil, err
+	}
+	return parent.breakoutRooms(meetings), nil
+}
+
+func (b3 *BigBlueButton) Recordings(meetings []string) ([]*Recording, error) {
+	return b3.RecordingsWithContext(context.Background(), meetings)
+}
+
+func (b3 *BigBlueButton) RecordingsWithContext(ctx context.Context, meetings []string) ([]*Recording, error) {
 	q := url.Values{}
 	if len(meetings) > 0 {
 		q.Set("meetingID", strings.Join(meetings, ","))
 	}
 	u := b3.makeURL("getRecordings", q)
-	res, err := http.Get(u.String())
+	res, err := b3.get(ctx, u.String())
 	if nil != err {
-		return []*Recording{}
+		return nil, err
 	}
-	defer res.Body.Close()
+	defer closeResponse(res)
 	return loadRecordingsResponse(res)
 }
 
-func (b3 *BigBlueButton) PublishRecordings(recordings []string, publish bool) bool {
-	if len(recordings) > 0 {
-		u := b3.makeURL("publishRecordings", url.Values{
-			"recordID": {strings.Join(recordings, ",")},
-			"publish":  {strconv.FormatBool(publish)},
-		})
-		res, err := http.Get(u.String())
-		if nil != err {
-			return false
-		}
-		defer res.Body.Close()
-		return loadBoolResponse(res, "published")
+func (b3 *BigBlueButton) PublishRecordings(recordings []string, publish bool) (bool, error) {
+	return b3.PublishRecordingsWithContext(context.Background(), recordings, publish)
+}
+
+func (b3 *BigBlueButton) PublishRecordingsWithContext(ctx context.Context, recordings []string, publish bool) (bool, error) {
+	action := "publishRecordings"
+	if len(recordings) < 1 {
+		return false, newMissingParamError(action, "RecordID")
+	}
+	u := b3.makeURL(action, url.Values{
+		"recordID": {strings.Join(recordings, ",")},
+		"publish":  {strconv.FormatBool(publish)},
+	})
+	res, err := b3.get(ctx, u.String())
+	if nil != err {
+		return false, err
+	}
+	defer closeResponse(res)
+	var response publishResponse
+	if err := loadResponse(res, action, &response); nil != err {
+		return false, err
 	}
-	return false
+	return bool(response.Published), nil
+}
+
+func (b3 *BigBlueButton) DeleteRecordings(recordings []string) (bool, error) {
+	return b3.DeleteRecordingsWithContext(context.Background(), recordings)
 }
 
-func (b3 *BigBlueButton) DeleteRecordings(recordings []string) bool {
-	if len(recordings) > 0 {
-		u := b3.makeURL("deleteRecordings", url.Values{
-			"recordID": {strings.Join(recordings, ",")},
-		})
-		res, err := http.Get(u.String())
-		if nil != err {
-			return false
+func (b3 *BigBlueButton) DeleteRecordingsWithContext(ctx context.Context, recordings []string) (bool, error) {
+	action := "deleteRecordings"
+	if len(recordings) < 1 {
+		return false, newMissingParamError(action, "RecordID")
+	}
+	u := b3.makeURL(action, url.Values{
+		"recordID": {strings.Join(recordings, ",")},
+	})
+	res, err := b3.get(ctx, u.String())
+	if nil != err {
+		return false, err
+	}
+	defer closeResponse(res)
+	var response deleteResponse
+	if err := loadResponse(res, action, &response); nil != err {
+		return false, err
+	}
+	return bool(response.Deleted), nil
+}
+
+// UpdateRecordings sets the given meta_* values on all recordings; an empty
+// value removes the key. The returned map holds the result for every record
+// ID. If the bulk update is rejected because some IDs do not exist, each ID
+// is retried on its own so that the others are still updated.
+func (b3 *BigBlueButton) UpdateRecordings(recordings []string, meta map[string]string) (map[string]error, error) {
+	return b3.UpdateRecordingsWithContext(context.Background(), recordings, meta)
+}
+
+func (b3 *BigBlueButton) UpdateRecordingsWithContext(ctx context.Context, recordings []string, meta map[string]string) (map[string]error, error) {
+	if len(recordings) < 1 {
+		return nil, newMissingParamError("updateRecordings", "RecordID")
+	}
+	results := make(map[string]error, len(recordings))
+	err := b3.updateRecordings(ctx, recordings, meta)
+	if nil != err && len(recordings) > 1 && IsNotFound(err) {
+		for _, id := range recordings {
+			results[id] = b3.updateRecordings(ctx, []string{id}, meta)
 		}
-		defer res.Body.Close()
-		return loadBoolResponse(res, "deleted")
+		return results, nil
+	}
+	if nil != err {
+		return nil, err
+	}
+	for _, id := range recordings {
+		results[id] = nil
+	}
+	return results, nil
+}
+
+func (b3 *BigBlueButton) updateRecordings(ctx context.Context, recordings []string, meta map[string]string) error {
+	action := "updateRecordings"
+	u := b3.makeURL(action, mergeUrlValues(
+		url.Values{"recordID": {strings.Join(recordings, ",")}},
+		metaValues(meta)))
+	res, err := b3.get(ctx, u.String())
+	if nil != err {
+		return err
+	}
+	defer closeResponse(res)
+	var response updateResponse
+	if err := loadResponse(res, action, &response); nil != err {
+		return err
+	} else if !response.Updated {
+		return &APIError{Action: action, ReturnCode: "SUCCESS", Message: "not updated", StatusCode: res.StatusCode}
 	}
-	return false
+	return nil
+}
+
+func (b3 *BigBlueButton) ServerVersion() (string, error) {
+	return b3.ServerVersionWithContext(context.Background())
 }
 
-func (b3 *BigBlueButton) ServerVersion() string {
-	res, err := http.Get(b3.Url.String())
+func (b3 *BigBlueButton) ServerVersionWithContext(ctx context.Context) (string, error) {
+	res, err := b3.get(ctx, b3.Url.String())
 	if nil != err {
-		return ""
+		return "", err
+	}
+	defer closeResponse(res)
+	var response versionResponse
+	if err := loadResponse(res, "", &response); nil != err {
+		return "", err
 	}
-	defer res.Body.Close()
-	return loadStringResponse(res, "version")
+	return response.Version, nil
 }
 
-func (b3 *BigBlueButton) checksum(action, params string) string {
-	if i := len(params) - 1; i > 0 && params[i] == '&' {
-		params = params[:i]
+// SignedURL returns the URL of an arbitrary API call, signed with the
+// server's secret. A checksum in query is ignored and query is not modified.
+func (b3 *BigBlueButton) SignedURL(action string, query url.Values) *url.URL {
+	params := url.Values{}
+	for k, v := range query {
+		if "checksum" != k {
+			params[k] = v
+		}
 	}
-	h := sha1.New()
-	io.WriteString(h, action)
-	io.WriteString(h, params)
-	io.WriteString(h, b3.Secret)
-	return fmt.Sprintf("%x", h.Sum(nil))
+	return b3.makeURL(action, params)
 }
 
 func (b3 *BigBlueButton) makeURL(action string, query url.Values) *url.URL {
@@ -203,6 +397,37 @@ func (b3 *BigBlueButton) makeURL(action string, query url.Values) *url.URL {
 	return u
 }
 
+func (b3 *BigBlueButton) client() *http.Client {
+	if nil != b3.Client {
+		return b3.Client
+	}
+	return http.DefaultClient
+}
+
+func (b3 *BigBlueButton) get(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if nil != err {
+		return nil, err
+	}
+	return b3.client().Do(req.WithContext(ctx))
+}
+
+func (b3 *BigBlueButton) post(ctx context.Context, u, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest("POST", u, body)
+	if nil != err {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return b3.client().Do(req.WithContext(ctx))
+}
+
+// closeResponse drains and closes the response body so that the underlying
+// connection can be reused.
+func closeResponse(res *http.Response) {
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+}
+
 func mergeUrlValues(values ...url.Values) (m url.Values) {
 	m = url.Values{}
 	for _, v := range values {
@@ -1,15 +1,466 @@
 package bbb
 
 import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
-func TestCreate(t *testing.T) {
+func TestJoinURL(t *testing.T) {
 	b3, _ := New("http://localhost/", "secret")
-	t.Log(b3.Create("123", EmptyOptions))
+	u, err := url.Parse(b3.JoinURL("Tim Jurcka", "123", "123", EmptyOptions))
+	if nil != err {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if "/join" != u.Path || "Tim Jurcka" != q.Get("fullName") || "123" != q.Get("meetingID") ||
+		b3.checksum("join", "fullName=Tim+Jurcka&meetingID=123&password=123") != q.Get("checksum") {
+		t.Errorf("unexpected join URL: %s", u)
+	}
 }
 
-func TestJoinURL(t *testing.T) {
+func TestMeetingInfoWithContext(t *testing.T) {
+	block := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer s.Close()
+	defer close(block)
+
+	b3, _ := New(s.URL+"/bigbluebutton/api/", "secret")
+	b3.Client = s.Client()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := b3.MeetingInfoWithContext(ctx, "123", "mp"); nil == err {
+		t.Fatal("expected error from cancelled request")
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	b3, s := newXMLServer(`<response><returncode>FAILED</returncode>` +
+		`<messageKey>checksumError</messageKey>` +
+		`<message>You did not pass the checksum security check</message></response>`)
+	defer s.Close()
+
+	meetings, err := b3.Meetings()
+	if nil != meetings || !IsChecksumError(err) {
+		t.Fatalf("expected checksumError, got %v, %v", meetings, err)
+	}
+	if e := err.(*APIError); "getMeetings" != e.Action || http.StatusOK != e.StatusCode {
+		t.Fatalf("unexpected error details: %#v", e)
+	}
+	if IsNotFound(err) || IsDuplicate(err) {
+		t.Fatalf("unexpected classification of %v", err)
+	}
+	if s := err.Error(); "bbb: getMeetings: FAILED checksumError: You did not pass the checksum security check" != s {
+		t.Fatalf("unexpected message %q", s)
+	}
+	if s := newHTTPError("create", http.StatusServiceUnavailable).Error(); "bbb: create: Service Unavailable (HTTP 503)" != s {
+		t.Fatalf("unexpected message %q", s)
+	}
+}
+
+func TestChecksum(t *testing.T) {
 	b3, _ := New("http://localhost/", "secret")
-	t.Log(b3.JoinURL("Tim Jurcka", "123", "123", EmptyOptions))
+	for a, expected := range map[ChecksumAlgorithm]string{
+		SHA1:   "7773b43c96213d4699d8d9a643216599f6a7e206",
+		SHA256: "57c2fa6ff9995c899981e5ac18c3af7b8111995776c20d2f932ddc6f8c9cae05",
+	} {
+		b3.Checksum = a
+		if sum := b3.checksum("getMeetings", "meetingID=123"); expected != sum {
+			t.Errorf("%s: expected %s, got %s", a, expected, sum)
+		}
+	}
+}
+
+func TestDetectChecksumAlgorithm(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if 64 != len(r.URL.Query().Get("checksum")) {
+			w.Write([]byte(`<response><returncode>FAILED</returncode>` +
+				`<messageKey>checksumError</messageKey></response>`))
+			return
+		}
+		w.Write([]byte(`<response><returncode>SUCCESS</returncode>` +
+			`<meetings/><messageKey>noMeetings</messageKey></response>`))
+	}))
+	defer s.Close()
+
+	b3, _ := New(s.URL+"/bigbluebutton/api/", "secret")
+	if a, err := b3.DetectChecksumAlgorithm(); nil != err || SHA256 != a || SHA256 != b3.Checksum {
+		t.Fatalf("expected sha256, got %s (%v)", a, err)
+	}
+}
+
+func TestRecordings(t *testing.T) {
+	b3, s := newXMLServer(`<response><returncode>SUCCESS</returncode><recordings>
+<recording>
+  <recordID>ffbfc4cc24428694e8b53a4e144f414052431693-1530718721124</recordID>
+  <meetingID>c637ba21adcd0191f48f5c4bf23fab0f96ed5c18</meetingID>
+  <name>Fred's Room</name>
+  <published>true</published>
+  <state>published</state>
+  <startTime>1530718721124</startTime>
+  <endTime>1530718810456</endTime>
+  <participants>3</participants>
+  <rawSize>951067</rawSize>
+  <metadata>
+    <meetingName>Fred's Room</meetingName>
+    <course>cs101</course>
+    <size>huge</size>
+  </metadata>
+  <size>1104836</size>
+  <playback>
+    <format>
+      <type>presentation</type>
+      <url>https://demo.bigbluebutton.org/playback/presentation/2.0/playback.html</url>
+      <processingTime>7177</processingTime>
+      <length>12</length>
+      <size>1104836</size>
+      <preview>
+        <images>
+          <image alt="Welcome" height="136" width="176">https://demo.bigbluebutton.org/thumb-1.png</image>
+          <image alt="Slide 2" height="136" width="176">https://demo.bigbluebutton.org/thumb-2.png</image>
+        </images>
+      </preview>
+    </format>
+    <format>
+      <type>video</type>
+      <url>https://demo.bigbluebutton.org/playback/video/</url>
+      <length>12</length>
+    </format>
+  </playback>
+</recording>
+</recordings></response>`)
+	defer s.Close()
+
+	recordings, err := b3.Recordings(nil)
+	if nil != err || 1 != len(recordings) {
+		t.Fatalf("expected one recording, got %v (%v)", recordings, err)
+	}
+	r := recordings[0]
+	if !r.Published || "published" != r.State || 3 != r.Participants ||
+		951067 != r.RawSize || 1104836 != r.Size {
+		t.Errorf("unexpected recording: %#v", r)
+	}
+	if "cs101" != r.Metadata["course"] || "huge" != r.Metadata["size"] {
+		t.Errorf("unexpected metadata: %v", r.Metadata)
+	}
+	if 1530718721 != r.StartTime.Unix() {
+		t.Errorf("unexpected start time: %v", r.StartTime)
+	}
+	if 2 != len(r.Playback) {
+		t.Fatalf("expected two playback formats, got %d", len(r.Playback))
+	}
+	p := r.Format("presentation")
+	if nil == p || 7177*time.Millisecond != p.ProcessingTime ||
+		12*time.Minute != p.Length || 2 != len(p.Preview) || 176 != p.Preview[1].Width ||
+		"https://demo.bigbluebutton.org/thumb-2.png" != p.Preview[1].Url {
+		t.Errorf("unexpected presentation playback: %#v", p)
+	}
+	if nil == r.Format("video") || nil != r.Format("podcast") {
+		t.Errorf("unexpected formats: %#v", r.Playback)
+	}
+
+	// Servers before 2.6 ignore offset and limit and send everything at once.
+	it, n := b3.IterateRecordings(context.Background(), &RecordingsOptions{Limit: 1}), 0
+	for it.Next() {
+		n++
+	}
+	if nil != it.Err() || 1 != n || -1 != it.Total() {
+		t.Errorf("unexpected iteration: %d, total %d (%v)", n, it.Total(), it.Err())
+	}
+}
+
+func newXMLServer(body string) (*BigBlueButton, *httptest.Server) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(body))
+	}))
+	b3, _ := New(s.URL+"/bigbluebutton/api/", "secret")
+	return &b3, s
+}
+
+func TestUpdateRecordings(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query(); strings.Contains(q.Get("recordID"), "missing") {
+			w.Write([]byte(`<response><returncode>FAILED</returncode>` +
+				`<messageKey>notFound</messageKey></response>`))
+		} else if "Lecture 1" != q.Get("meta_name") {
+			w.Write([]byte(`<response><returncode>FAILED</returncode></response>`))
+		} else {
+			w.Write([]byte(`<response><returncode>SUCCESS</returncode><updated>true</updated></response>`))
+		}
+	}))
+	defer s.Close()
+
+	b3, _ := New(s.URL+"/bigbluebutton/api/", "secret")
+	results, err := b3.UpdateRecordings([]string{"a", "missing", "b"}, map[string]string{"name": "Lecture 1"})
+	if nil != err || 3 != len(results) {
+		t.Fatalf("unexpected results: %v (%v)", results, err)
+	}
+	if nil != results["a"] || nil != results["b"] || !IsNotFound(results["missing"]) {
+		t.Errorf("unexpected results: %v", results)
+	}
+}
+
+func TestPutRecordingTextTrack(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if "broken" == q.Get("lang") {
+			w.Write([]byte(`{"response":{"returncode":`))
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if nil != err || "en-US" != q.Get("lang") || "subtitles" != q.Get("kind") {
+			w.Write([]byte(`{"response":{"returncode":"FAILED","messageKey":"paramError"}}`))
+			return
+		}
+		defer file.Close()
+		if data, _ := ioutil.ReadAll(file); "WEBVTT\n" != string(data) ||
+			"text/vtt" != header.Header.Get("Content-Type") {
+			w.Write([]byte(`{"response":{"returncode":"FAILED","messageKey":"invalidFile"}}`))
+			return
+		}
+		w.Write([]byte(`{"response":{"returncode":"SUCCESS","recordId":"abc"}}`))
+	}))
+	defer s.Close()
+
+	b3, _ := New(s.URL+"/bigbluebutton/api/", "secret")
+	options := &TextTrackOptions{Lang: "en-US", Label: "English", Filename: "en.vtt"}
+	if err := b3.PutRecordingTextTrack("abc", options, strings.NewReader("WEBVTT\n")); nil != err {
+		t.Fatal(err)
+	}
+	options.Lang = ""
+	if err := b3.PutRecordingTextTrack("abc", options, strings.NewReader("WEBVTT\n")); nil == err {
+		t.Fatal("expected paramError")
+	}
+	options.Lang = "broken"
+	var apiErr *APIError
+	if err := b3.PutRecordingTextTrack("abc", options, strings.NewReader("WEBVTT\n")); !errors.As(err, &apiErr) {
+		t.Errorf("expected *APIError for malformed response, got %#v", err)
+	}
+}
+
+func TestMeetingMetadata(t *testing.T) {
+	options := &CreateOptions{Name: "Test", Metadata: map[string]string{"course": "cs101"}}
+	if v := options.Values(); "cs101" != v.Get("meta_course") || "" != v.Get("metadata") {
+		t.Errorf("unexpected create values: %v", v)
+	}
+
+	b3, s := newXMLServer(`<response><returncode>SUCCESS</returncode>
+<meetingName>Test</meetingName><meetingID>123</meetingID><running>true</running>
+<participantCount>1</participantCount>
+<attendees><attendee><userID>u1</userID><fullName>Tim</fullName><role>MODERATOR</role></attendee></attendees>
+<metadata><course>cs101</course><tenant>acme</tenant></metadata>
+</response>`)
+	defer s.Close()
+
+	m, err := b3.MeetingInfo("123", "mp")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "cs101" != m.Metadata["course"] || "acme" != m.Metadata["tenant"] || 2 != len(m.Metadata) {
+		t.Errorf("unexpected metadata: %v", m.Metadata)
+	}
+	if !m.Running || 1 != m.NumUsers || 1 != len(m.Attendees) || "MODERATOR" != m.Attendees[0].Role {
+		t.Errorf("unexpected meeting: %#v", m)
+	}
+}
+
+func TestBreakoutRooms(t *testing.T) {
+	b3, s := newXMLServer(`<response><returncode>SUCCESS</returncode><meetings>
+<meeting><meetingID>parent</meetingID><internalMeetingID>p-1</internalMeetingID>
+  <isBreakout>false</isBreakout>
+  <breakoutRooms><breakout>b-2</breakout><breakout>b-1</breakout></breakoutRooms></meeting>
+<meeting><meetingID>room-2</meetingID><internalMeetingID>b-2</internalMeetingID>
+  <isBreakout>true</isBreakout>
+  <breakout><parentMeetingID>p-1</parentMeetingID><sequence>2</sequence><freeJoin>true</freeJoin></breakout></meeting>
+<meeting><meetingID>room-1</meetingID><internalMeetingID>b-1</internalMeetingID>
+  <isBreakout>true</isBreakout>
+  <breakout><parentMeetingID>p-1</parentMeetingID><sequence>1</sequence><freeJoin>false</freeJoin></breakout></meeting>
+<meeting><meetingID>other</meetingID><internalMeetingID>o-1</internalMeetingID></meeting>
+</meetings></response>`)
+	defer s.Close()
+
+	meetings, err := b3.Meetings()
+	if nil != err || 4 != len(meetings) {
+		t.Fatalf("unexpected meetings: %v (%v)", meetings, err)
+	}
+	parent := meetings[0]
+	if parent.IsBreakout || 2 != len(parent.BreakoutRooms) {
+		t.Errorf("unexpected parent: %#v", parent)
+	}
+	rooms, err := b3.BreakoutRooms(parent)
+	if nil != err || 2 != len(rooms) {
+		t.Fatalf("unexpected breakout rooms: %v (%v)", rooms, err)
+	}
+	if "room-1" != rooms[0].Id || "room-2" != rooms[1].Id || !rooms[1].FreeJoin ||
+		"p-1" != rooms[0].ParentMeetingID {
+		t.Errorf("unexpected breakout rooms: %#v, %#v", rooms[0], rooms[1])
+	}
+}
+
+func TestHooks(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		checksum := q.Get("checksum")
+		q.Del("checksum")
+		b3 := BigBlueButton{Secret: "secret"}
+		if "/bigbluebutton/api/hooks/create" != r.URL.Path ||
+			b3.checksum("hooks/create", q.Encode()) != checksum {
+			w.Write([]byte(`<response><returncode>FAILED</returncode>` +
+				`<messageKey>checksumError</messageKey></response>`))
+			return
+		}
+		w.Write([]byte(`<response><returncode>SUCCESS</returncode><hookID>7</hookID>` +
+			`<permanentHook>false</permanentHook><rawData>true</rawData></response>`))
+	}))
+	defer s.Close()
+
+	b3, _ := New(s.URL+"/bigbluebutton/api/", "secret")
+	hook, err := b3.Hooks().Create("http://example.com/hook", &HookOptions{MeetingId: "123", GetRaw: true})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "7" != hook.Id || !hook.Raw || "123" != hook.MeetingId || "http://example.com/hook" != hook.CallbackURL {
+		t.Errorf("unexpected hook: %#v", hook)
+	}
+}
+
+func TestAttendees(t *testing.T) {
+	b3, s := newXMLServer(`<response><returncode>SUCCESS</returncode><meetingID>123</meetingID>
+<attendees><attendee>
+  <userID>u1</userID><fullName>Tim</fullName><role>MODERATOR</role>
+  <isPresenter>true</isPresenter><isListeningOnly>false</isListeningOnly>
+  <hasJoinedVoice>true</hasJoinedVoice><hasVideo>true</hasVideo><clientType>HTML5</clientType>
+  <customdata><bbb_auto_join_audio>false</bbb_auto_join_audio></customdata>
+</attendee><attendee>
+  <userID>u2</userID><fullName>Tom</fullName><role>VIEWER</role>
+  <isListeningOnly>true</isListeningOnly><clientType>FLASH</clientType>
+</attendee></attendees></response>`)
+	defer s.Close()
+
+	m, err := b3.MeetingInfo("123", "mp")
+	if nil != err || 2 != len(m.Attendees) {
+		t.Fatalf("unexpected meeting: %v (%v)", m, err)
+	}
+	tim, tom := m.Attendees[0], m.Attendees[1]
+	if !tim.IsPresenter || !tim.HasJoinedVoice || !tim.HasVideo || tim.IsListeningOnly ||
+		"HTML5" != tim.ClientType || "false" != tim.CustomData["bbb_auto_join_audio"] {
+		t.Errorf("unexpected attendee: %#v", tim)
+	}
+	if tom.IsPresenter || !tom.IsListeningOnly || tom.HasVideo || "FLASH" != tom.ClientType ||
+		0 != len(tom.CustomData) {
+		t.Errorf("unexpected attendee: %#v", tom)
+	}
+}
+
+func TestCreateOptionValues(t *testing.T) {
+	options := &CreateOptions{
+		Name:                    "Test",
+		Duration:                90 * time.Minute,
+		GuestPolicy:             GuestPolicyAskModerator,
+		MuteOnStart:             Bool(true),
+		AllowStartStopRecording: Bool(false),
+		LockSettingsLockOnJoin:  Bool(false),
+		MeetingCameraCap:        new(int),
+		DisabledFeatures:        []string{"chat", "polls"},
+		BannerText:              "Exam in progress",
+	}
+	expected := map[string]string{
+		"name":                    "Test",
+		"duration":                "90",
+		"guestPolicy":             "ASK_MODERATOR",
+		"muteOnStart":             "true",
+		"allowStartStopRecording": "false",
+		"lockSettingsLockOnJoin":  "false",
+		"meetingCameraCap":        "0",
+		"disabledFeatures":        "chat,polls",
+		"bannerText":              "Exam in progress",
+	}
+	v := options.Values()
+	for k, value := range expected {
+		if value != v.Get(k) {
+			t.Errorf("%s: expected %q, got %q", k, value, v.Get(k))
+		}
+	}
+	for _, k := range []string{"record", "webcamsOnlyForModerator", "userCameraCap", "maxParticipants", "logo"} {
+		if _, found := v[k]; found {
+			t.Errorf("%s: expected to be omitted, got %q", k, v.Get(k))
+		}
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	b3, _ := New("http://localhost/bigbluebutton/api/", "secret")
+	b3.Checksum = SHA256
+	u := b3.makeURL("getMeetingInfo", url.Values{"meetingID": {"a b&c"}, "password": {"mp"}})
+	params, a, err := VerifyChecksum("getMeetingInfo", u.RawQuery, "old", "secret")
+	if nil != err || SHA256 != a || "a b&c" != params.Get("meetingID") || "" != params.Get("checksum") {
+		t.Errorf("unexpected result: %v %s (%v)", params, a, err)
+	}
+	for action, query := range map[string]string{
+		"getMeetingInfo":   u.RawQuery + "&password=ap",
+		"end":              u.RawQuery,
+		"getMeetings":      "",
+		"isMeetingRunning": "meetingID=1&checksum=" + strings.Repeat("0", 39),
+	} {
+		if _, _, err := VerifyChecksum(action, query, "secret"); !IsChecksumError(err) {
+			t.Errorf("%s %s: expected checksumError, got %v", action, query, err)
+		}
+	}
+	if _, _, err := VerifyChecksum("getMeetingInfo", u.RawQuery, "other"); !IsChecksumError(err) {
+		t.Errorf("expected checksumError for wrong secret, got %v", err)
+	}
+	if _, a, err := VerifyChecksum("getMeetings", "checksum="+SHA1.Sum("getMeetings", "", "secret"), "secret"); nil != err || SHA1 != a {
+		t.Errorf("unexpected result without parameters: %s (%v)", a, err)
+	}
+}
+
+func TestParseJoinURL(t *testing.T) {
+	b3, _ := New("http://localhost/bigbluebutton/api/", "secret")
+	options := &JoinOptions{
+		CreateTime: time.Unix(1531240585, 189*int64(time.Millisecond)),
+		UserId:     "42",
+		Role:       RoleModerator,
+		Guest:      true,
+		Redirect:   Bool(false),
+		UserData:   map[string]string{"bbb_skip_check_audio": "true"},
+	}
+	u := b3.JoinURL("Tim Jurcka", "123", "", options) + "&custom=1"
+	r, err := ParseJoinURL(u)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "Tim Jurcka" != r.Name || "123" != r.MeetingID || "" != r.Password || "42" != r.Options.UserId ||
+		RoleModerator != r.Options.Role || !r.Options.Guest || nil == r.Options.Redirect || *r.Options.Redirect ||
+		!options.CreateTime.Equal(r.Options.CreateTime) || "true" != r.Options.UserData["bbb_skip_check_audio"] {
+		t.Errorf("unexpected join request: %#v", r)
+	}
+	if "1" != r.Extra.Get("custom") || 1 != len(r.Extra) {
+		t.Errorf("unexpected extra parameters: %v", r.Extra)
+	}
+	if _, err := r.Verify("secret"); !IsChecksumError(err) {
+		t.Errorf("expected checksumError for appended parameter, got %v", err)
+	}
+	r, _ = ParseJoinURL(b3.JoinURL("Tim", "123", "ap", EmptyOptions))
+	if a, err := r.Verify("old", "secret"); nil != err || SHA1 != a || "ap" != r.Password {
+		t.Errorf("unexpected verification: %s (%v)", a, err)
+	}
+
+	for _, u := range []string{
+		"http://localhost/bigbluebutton/api/create?meetingID=1",
+		"http://localhost/bigbluebutton/api/join?fullName=Tim",
+		"http://localhost/bigbluebutton/api/join?meetingID=1&guest=maybe",
+	} {
+		if _, err := ParseJoinURL(u); nil == err {
+			t.Errorf("%s: expected error", u)
+		}
+	}
 }
@@ -89,14 +89,22 @@ func logging(output string) {
 }
 
 func PgIndex(w http.ResponseWriter, req *http.Request) {
+	version, err := b3.ServerVersion()
+	if nil != err {
+		log.Println("ServerVersion:", err)
+	}
+	meetings, err := b3.Meetings()
+	if nil != err {
+		log.Println("Meetings:", err)
+	}
 	data := struct {
 		ServerVersion string
 		ServerURL     string
 		Meetings      []*bbb.Meeting
 	}{
-		b3.ServerVersion(),
+		version,
 		b3.Url.String(),
-		b3.Meetings(),
+		meetings,
 	}
 	if err := templates.ExecuteTemplate(w, "index.html", data); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -143,7 +151,12 @@ func PgCreate(w http.ResponseWriter, req *http.Request) {
 
 func PgInfo(w http.ResponseWriter, req *http.Request) {
 	id := req.FormValue("id")
-	for _, meeting := range b3.Meetings() {
+	meetings, err := b3.Meetings()
+	if nil != err {
+		http.Error(w, err.Error(), http.StatusTeapot)
+		return
+	}
+	for _, meeting := range meetings {
 		if meeting.Id == id {
 			log.Printf("%#v", meeting)
 			if meeting, err := b3.MeetingInfo(id, meeting.ModeratorPW); nil == err {
@@ -167,7 +180,12 @@ func PgJoin(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 	}
-	for _, meeting := range b3.Meetings() {
+	meetings, err := b3.Meetings()
+	if nil != err {
+		http.Error(w, err.Error(), http.StatusTeapot)
+		return
+	}
+	for _, meeting := range meetings {
 		if meeting.Id == i {
 			password := meeting.AttendeePW
 			if m == "1" {
@@ -68,6 +68,16 @@ func init() {
 				txid = addEventId(&event)
 				handlerFunc = HandleDeleteRecordings
 				responder = uhMkIdResponder(txid)
+			case "recordings.update":
+				txid = addEventId(&event)
+				handlerFunc = HandleUpdateRecordings
+				responder = uhMkIdResponder(txid)
+			case "recordings.tracks":
+				handlerFunc = HandleRecordingTextTracks
+			case "document.insert":
+				txid = addEventId(&event)
+				handlerFunc = HandleInsertDocument
+				responder = uhMkIdResponder(txid)
 			case "config.default":
 				handlerFunc = HandleDefaultConfigXML
 			case "config.set":
@@ -78,7 +88,7 @@ func init() {
 				}
 			}
 
-			client := &Client{address: req.RemoteAddr, b3: b3, events: make(chan WsEvent, 1)}
+			client := &Client{address: req.RemoteAddr, b3: &b3, events: make(chan WsEvent, 1)}
 			handler.AddClient(client)
 			defer handler.RemoveClient(client)
 
@@ -129,6 +139,5 @@ func uhMkIdResponder(id string) uhResponderFunc {
 				return json.Marshal(ev)
 			}
 		}
-		return nil, nil
 	}
 }
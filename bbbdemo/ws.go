@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"log"
 	"net/http"
 	"sync"
+	"time"
 
 	"code.google.com/p/go.net/websocket"
 	"github.com/sdgoij/gobbb"
@@ -25,11 +27,12 @@ func HandleConnect(c *Client, event WsEvent) error {
 		"version": "",
 	}}
 	if err == nil {
-		if version := b3.ServerVersion(); "" == version {
+		if version, oops := b3.ServerVersion(); nil != oops {
 			ev.Data["status"] = "failure"
+			ev.Data["error"] = oops.Error()
 		} else {
 			ev.Data["version"] = version
-			c.b3 = b3
+			c.b3 = &b3
 		}
 	} else {
 		ev.Data["error"] = err.Error()
@@ -46,7 +49,7 @@ func HandleCreate(c *Client, event WsEvent) error {
 	var options bbb.CreateOptions
 	eventToOptions(event, &options)
 
-	if m, err := c.b3.Create(id, &options); nil != err {
+	if m, err := c.b3.CreateWithContext(context.Background(), id, &options); nil != err {
 		ev := WsEvent{"create.fail", WsEventData{"error": err.Error()}}
 		if v, t := event.Data["__txid"]; t {
 			ev.Data["__txid"] = v.(string)
@@ -55,7 +58,7 @@ func HandleCreate(c *Client, event WsEvent) error {
 	} else {
 		ev := WsEvent{"create.success", WsEventData{
 			"id":          m.Id,
-			"created":     m.CreateTime.Unix(),
+			"created":     unix(m.CreateTime),
 			"attendeePW":  m.AttendeePW,
 			"moderatorPW": m.ModeratorPW,
 			"forcedEnd":   m.ForcedEnd,
@@ -102,10 +105,15 @@ func HandleEnd(c *Client, event WsEvent) error {
 	if v, t := event.Data["__txid"]; t {
 		ev.Data["__txid"] = v.(string)
 	}
-	if ok := b3.End(id, password); ok {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := c.b3.EndWithContext(ctx, id, password, &bbb.EndOptions{
+		Wait: bbb.EndWaitNotRunning,
+	}); nil == err {
 		ev.Data["ended"] = true
 		c.handler.Broadcast(ev)
 	} else {
+		ev.Data["error"] = err.Error()
 		c.events <- ev
 	}
 	return nil
@@ -116,8 +124,13 @@ func HandleIsMeetingRunning(c *Client, event WsEvent) error {
 	if v, t := event.Data["id"]; t && nil != v {
 		id = v.(string)
 	}
+	running, err := c.b3.IsMeetingRunningWithContext(context.Background(), id)
+	if nil != err {
+		c.events <- WsEvent{"running.fail", WsEventData{"error": err.Error()}}
+		return nil
+	}
 	c.events <- WsEvent{"running", WsEventData{
-		"running": c.b3.IsMeetingRunning(id)},
+		"running": running},
 	}
 	return nil
 }
@@ -130,7 +143,7 @@ func HandleMeetingInfo(c *Client, event WsEvent) error {
 	if v, t := event.Data["password"]; t && nil != v {
 		password = v.(string)
 	}
-	m, err := c.b3.MeetingInfo(id, password)
+	m, err := c.b3.MeetingInfoWithContext(context.Background(), id, password)
 	if nil != err {
 		c.events <- WsEvent{"info.fail", WsEventData{"error": err.Error()}}
 		return nil
@@ -138,40 +151,57 @@ func HandleMeetingInfo(c *Client, event WsEvent) error {
 	attendees := make([]WsEventData, len(m.Attendees))
 	for k, v := range m.Attendees {
 		attendees[k] = WsEventData{
-			"userID": v.UserId,
-			"name":   v.Name,
-			"role":   v.Role,
+			"userID":          v.UserId,
+			"internalUserID":  v.InternalUserId,
+			"name":            v.Name,
+			"role":            v.Role,
+			"isPresenter":     v.IsPresenter,
+			"isListeningOnly": v.IsListeningOnly,
+			"hasJoinedVoice":  v.HasJoinedVoice,
+			"hasVideo":        v.HasVideo,
+			"clientType":      v.ClientType,
+			"customData":      v.CustomData,
 		}
 	}
 	c.events <- WsEvent{"info.succsess", WsEventData{
 		"id":          m.Id,
 		"name":        m.Name,
-		"created":     m.CreateTime.Unix(),
+		"created":     unix(m.CreateTime),
 		"attendeePW":  m.AttendeePW,
 		"moderatorPW": m.ModeratorPW,
 		"running":     m.Running,
 		"recording":   m.Recording,
 		"forcedEnd":   m.ForcedEnd,
-		"startTime":   m.StartTime.Unix(),
-		"endTime":     m.EndTime.Unix(),
+		"startTime":   unix(m.StartTime),
+		"endTime":     unix(m.EndTime),
 		"numUsers":    m.NumUsers,
 		"maxUsers":    m.MaxUsers,
 		"numMod":      m.NumMod,
 		"attendees":   attendees,
+		"metadata":    m.Metadata,
+
+		"isBreakout":      m.IsBreakout,
+		"parentMeetingID": m.ParentMeetingID,
+		"breakoutRooms":   m.BreakoutRooms,
 	}}
 	return nil
 }
 
 func HandleMeetings(c *Client, event WsEvent) error {
-	meetings := c.b3.Meetings()
+	meetings, err := c.b3.MeetingsWithContext(context.Background())
+	if nil != err {
+		c.events <- WsEvent{"meetings.fail", WsEventData{"error": err.Error()}}
+		return nil
+	}
 	ev := make([]WsEventData, len(meetings))
 	for k, m := range meetings {
 		ev[k] = WsEventData{
 			"id":          m.Id,
-			"created":     m.CreateTime.Unix(),
+			"created":     unix(m.CreateTime),
 			"attendeePW":  m.AttendeePW,
 			"moderatorPW": m.ModeratorPW,
 			"forcedEnd":   m.ForcedEnd,
+			"isBreakout":  m.IsBreakout,
 		}
 	}
 	c.events <- WsEvent{"meetings", WsEventData{"meetings": ev}}
@@ -183,20 +213,46 @@ func HandleRecordings(c *Client, event WsEvent) (err error) {
 	if v, t := event.Data["meetings"]; t {
 		meetings = itos(v)
 	}
-	recordings := c.b3.Recordings(meetings)
+	recordings, err := c.b3.RecordingsWithContext(context.Background(), meetings)
+	if nil != err {
+		c.events <- WsEvent{"recordings.fail", WsEventData{"error": err.Error()}}
+		return nil
+	}
 	ev := make([]WsEventData, len(recordings))
 	for k, r := range recordings {
+		playback := make([]WsEventData, len(r.Playback))
+		for i, p := range r.Playback {
+			preview := make([]WsEventData, len(p.Preview))
+			for j, image := range p.Preview {
+				preview[j] = WsEventData{
+					"url":    image.Url,
+					"alt":    image.Alt,
+					"width":  image.Width,
+					"height": image.Height,
+				}
+			}
+			playback[i] = WsEventData{
+				"type":           p.Type,
+				"url":            p.Url,
+				"len":            int(p.Length / time.Minute),
+				"processingTime": int64(p.ProcessingTime / time.Millisecond),
+				"size":           p.Size,
+				"preview":        preview,
+			}
+		}
 		ev[k] = WsEventData{
-			"recordId":  r.RecordId,
-			"meetingId": r.MeetingId,
-			"name":      r.Name,
-			"startTime": r.StartTime.Unix(),
-			"endTime":   r.EndTime.Unix(),
-			"playback": WsEventData{
-				"type": r.Playback.Type,
-				"url":  r.Playback.Url,
-				"len":  r.Playback.Len,
-			},
+			"recordId":     r.RecordId,
+			"meetingId":    r.MeetingId,
+			"name":         r.Name,
+			"published":    r.Published,
+			"state":        r.State,
+			"startTime":    unix(r.StartTime),
+			"endTime":      unix(r.EndTime),
+			"participants": r.Participants,
+			"rawSize":      r.RawSize,
+			"size":         r.Size,
+			"metadata":     r.Metadata,
+			"playback":     playback,
 		}
 	}
 	c.events <- WsEvent{"recordings", WsEventData{"recordings": ev}}
@@ -218,7 +274,10 @@ func HandlePublishRecordings(c *Client, event WsEvent) error {
 	if v, t := event.Data["__txid"]; t {
 		ev.Data["__txid"] = v.(string)
 	}
-	if c.b3.PublishRecordings(recordings, publish) {
+	if published, err := c.b3.PublishRecordingsWithContext(context.Background(), recordings, publish); nil != err {
+		ev.Data["error"] = err.Error()
+		c.events <- ev
+	} else if published {
 		ev.Data["published"] = true
 		c.handler.Broadcast(ev)
 	} else {
@@ -239,7 +298,10 @@ func HandleDeleteRecordings(c *Client, event WsEvent) error {
 	if v, t := event.Data["__txid"]; t {
 		ev.Data["__txid"] = v.(string)
 	}
-	if c.b3.DeleteRecordings(recordings) {
+	if deleted, err := c.b3.DeleteRecordingsWithContext(context.Background(), recordings); nil != err {
+		ev.Data["error"] = err.Error()
+		c.events <- ev
+	} else if deleted {
 		ev.Data["deleted"] = true
 		c.handler.Broadcast(ev)
 	} else {
@@ -248,8 +310,97 @@ func HandleDeleteRecordings(c *Client, event WsEvent) error {
 	return nil
 }
 
+func HandleUpdateRecordings(c *Client, event WsEvent) error {
+	var recordings []string
+	meta := map[string]string{}
+	if v, t := event.Data["recordings"]; t {
+		recordings = itos(v)
+	}
+	if v, t := event.Data["meta"]; t {
+		if err := jsoncp(&meta, v); nil != err {
+			return err
+		}
+	}
+	ev := WsEvent{"recordings", WsEventData{
+		"recordings": recordings,
+		"updated":    WsEventData{},
+	}}
+	if v, t := event.Data["__txid"]; t {
+		ev.Data["__txid"] = v.(string)
+	}
+	results, err := c.b3.UpdateRecordingsWithContext(context.Background(), recordings, meta)
+	if nil != err {
+		ev.Data["error"] = err.Error()
+		c.events <- ev
+		return nil
+	}
+	for id, err := range results {
+		if nil != err {
+			ev.Data["updated"].(WsEventData)[id] = err.Error()
+		} else {
+			ev.Data["updated"].(WsEventData)[id] = true
+		}
+	}
+	c.handler.Broadcast(ev)
+	return nil
+}
+
+func HandleRecordingTextTracks(c *Client, event WsEvent) error {
+	id := ""
+	if v, t := event.Data["id"]; t && nil != v {
+		id = v.(string)
+	}
+	tracks, err := c.b3.RecordingTextTracksWithContext(context.Background(), id)
+	if nil != err {
+		c.events <- WsEvent{"recordings.tracks.fail", WsEventData{"error": err.Error()}}
+		return nil
+	}
+	ev := make([]WsEventData, len(tracks))
+	for k, track := range tracks {
+		ev[k] = WsEventData{
+			"href":   track.Href,
+			"kind":   track.Kind,
+			"label":  track.Label,
+			"lang":   track.Lang,
+			"source": track.Source,
+		}
+	}
+	c.events <- WsEvent{"recordings.tracks", WsEventData{"id": id, "tracks": ev}}
+	return nil
+}
+
+func HandleInsertDocument(c *Client, event WsEvent) error {
+	id := ""
+	if v, t := event.Data["id"]; t && nil != v {
+		id = v.(string)
+	}
+	var docs []bbb.ConfigXML_Document
+	if v, t := event.Data["documents"]; t {
+		if err := jsoncp(&docs, v); nil != err {
+			return err
+		}
+	}
+	if err := c.b3.InsertDocumentWithContext(context.Background(), id, docs); nil != err {
+		ev := WsEvent{"document.insert.fail", WsEventData{"error": err.Error()}}
+		if v, t := event.Data["__txid"]; t {
+			ev.Data["__txid"] = v.(string)
+		}
+		c.events <- ev
+	} else {
+		ev := WsEvent{"document.insert.success", WsEventData{
+			"id":        id,
+			"documents": len(docs),
+		}}
+		if v, t := event.Data["__txid"]; t {
+			ev.Data["__txid"] = v.(string)
+		}
+		c.handler.Broadcast(ev)
+	}
+	return nil
+}
+
 func HandleDefaultConfigXML(c *Client, event WsEvent) error {
-	if conf, err := c.b3.DefaultConfigXML(); nil != err {
+	if conf, err := c.b3.DefaultConfigXMLWithContext(context.Background()); nil != err {
 		c.events <- WsEvent{"config.error", WsEventData{
 			"error": err.Error(),
 		}}
@@ -276,7 +427,7 @@ func HandleSetConfigXML(c *Client, event WsEvent) error {
 	if err := jsoncp(&conf, config); nil != err {
 		return err
 	}
-	if token, err := c.b3.SetConfigXML(meeting, &conf); nil != err {
+	if token, err := c.b3.SetConfigXMLWithContext(context.Background(), meeting, &conf); nil != err {
 		c.events <- WsEvent{"config.error", WsEventData{
 			"error": err.Error(),
 		}}
@@ -299,9 +450,13 @@ var handler *WsEventHandler = &WsEventHandler{
 		"info":     HandleMeetingInfo,
 		"meetings": HandleMeetings,
 
+		"document.insert": HandleInsertDocument,
+
 		"recordings":         HandleRecordings,
 		"recordings.publish": HandlePublishRecordings,
 		"recordings.delete":  HandleDeleteRecordings,
+		"recordings.update":  HandleUpdateRecordings,
+		"recordings.tracks":  HandleRecordingTextTracks,
 
 		"config.default": HandleDefaultConfigXML,
 		"config.set":     HandleSetConfigXML,
@@ -338,7 +493,7 @@ func HandleWS(ws *websocket.Conn) {
 type Client struct {
 	address string
 	conn    *websocket.Conn
-	b3      bbb.BigBlueButton
+	b3      bbb.API
 	done    chan struct{}
 	events  chan WsEvent
 	handler *WsEventHandler
@@ -465,3 +620,11 @@ func jsoncp(dst, src interface{}) error {
 	}
 	return nil
 }
+
+// unix returns t in seconds since the epoch, or 0 if t is unset.
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sdgoij/gobbb"
+)
+
+const AdminPath = "/servers/"
+
+type serverJSON struct {
+	Name           string    `json:"name"`
+	State          string    `json:"state"`
+	Since          time.Time `json:"since"`
+	Healthy        bool      `json:"healthy"`
+	Meetings       int       `json:"meetings"`
+	Running        int       `json:"running"`
+	Users          int       `json:"users"`
+	Updated        time.Time `json:"updated"`
+	Error          string    `json:"error,omitempty"`
+	SafeToShutDown bool      `json:"safeToShutDown"`
+}
+
+func newServerJSON(n bbb.PoolNode) serverJSON {
+	s := serverJSON{
+		Name:           n.Name,
+		State:          n.State.String(),
+		Since:          n.Since,
+		Healthy:        n.Healthy,
+		Meetings:       n.Meetings,
+		Running:        n.Running,
+		Users:          n.Users,
+		Updated:        n.Updated,
+		SafeToShutDown: n.SafeToShutDown(),
+	}
+	if nil != n.Err {
+		s.Error = n.Err.Error()
+	}
+	return s
+}
+
+// AdminHandler serves the state of the servers as JSON and lets operators
+// drain, disable and enable them:
+//
+//	GET  /servers/
+//	GET  /servers/<name>
+//	POST /servers/<name>  state=active|draining|disabled
+//
+// It does no authentication and should only be reachable by operators.
+func (p *Proxy) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasPrefix(req.URL.Path+"/", AdminPath) {
+			http.NotFound(w, req)
+			return
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, AdminPath), "/")
+		if "" == name {
+			if "GET" != req.Method {
+				http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+				return
+			}
+			list := []serverJSON{}
+			for _, n := range p.pool.Nodes() {
+				list = append(list, newServerJSON(n))
+			}
+			writeJSON(w, list)
+			return
+		}
+		switch req.Method {
+		case "GET":
+		case "POST", "PUT":
+			state, err := bbb.ParseNodeState(req.FormValue("state"))
+			if nil != err {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := p.pool.SetState(name, state); nil != err {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+		default:
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		for _, n := range p.pool.Nodes() {
+			if name == n.Name {
+				writeJSON(w, newServerJSON(n))
+				return
+			}
+		}
+		http.NotFound(w, req)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if nil != err {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,105 @@
+// Command bbbproxy serves the BigBlueButton API with its own URL and secret
+// and spreads the meetings over several BigBlueButton servers, so that
+// existing integrations can use a cluster as if it were a single server.
+//
+//	bbbproxy -proxy.secret=s3cr3t \
+//		-server=bbb1,https://bbb1.example.com/bigbluebutton/api/,secret1 \
+//		-server=bbb2,https://bbb2.example.com/bigbluebutton/api/,secret2
+//
+// Servers are drained before maintenance with the admin API, which listens
+// on a separate address and reports when a server is safe to shut down:
+//
+//	curl -d state=draining http://127.0.0.1:8091/servers/bbb1
+//	curl http://127.0.0.1:8091/servers/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sdgoij/gobbb"
+)
+
+var (
+	flagHttpAddr    = flag.String("http.addr", ":8090", "HTTP service address (e.g., ':8090')")
+	flagAdminAddr   = flag.String("admin.addr", "127.0.0.1:8091", "Admin API address; disabled if empty")
+	flagSecret      = flag.String("proxy.secret", "", "Secret clients use to sign API calls")
+	flagRefresh     = flag.Duration("pool.refresh", 30*time.Second, "Interval between polls of the servers' meetings")
+	flagMaxBodySize = flag.Int64("proxy.maxbody", DefaultMaxBodySize, "Maximum size of create and insertDocument bodies")
+	flagServers     servers
+)
+
+type server struct {
+	name, url, secret string
+}
+
+// servers collects the -server flags, given as name,url,secret.
+type servers []server
+
+func (s *servers) String() string {
+	names := []string{}
+	for _, server := range *s {
+		names = append(names, server.name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (s *servers) Set(v string) error {
+	parts := strings.SplitN(v, ",", 3)
+	if len(parts) < 3 || "" == parts[0] || "" == parts[1] {
+		return fmt.Errorf("invalid server %q, expected name,url,secret", v)
+	}
+	*s = append(*s, server{parts[0], parts[1], parts[2]})
+	return nil
+}
+
+func init() {
+	flag.Var(&flagServers, "server", "BigBlueButton server as name,url,secret; may be repeated")
+}
+
+func main() {
+	flag.Parse()
+	if "" == *flagSecret || 0 == len(flagServers) {
+		log.Fatal("bbbproxy: -proxy.secret and at least one -server are required")
+	}
+	proxy := NewProxy(*flagSecret)
+	proxy.MaxBodySize = *flagMaxBodySize
+	for _, s := range flagServers {
+		b3, err := bbb.New(s.url, s.secret)
+		if nil != err {
+			log.Fatalf("bbbproxy: %s: %v", s.name, err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if a, err := b3.DetectChecksumAlgorithmWithContext(ctx); nil != err {
+			log.Printf("%s: checksum detection failed, using %s: %v", s.name, a, err)
+		}
+		cancel()
+		if err := proxy.Add(s.name, &b3); nil != err {
+			log.Fatalf("bbbproxy: %v", err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := proxy.Pool().Refresh(ctx); nil != err {
+		log.Printf("bbbproxy: initial refresh: %v", err)
+	}
+	cancel()
+	go proxy.Pool().Run(context.Background(), *flagRefresh)
+	if "" != *flagAdminAddr {
+		go func() {
+			log.Fatal(http.ListenAndServe(*flagAdminAddr, Log(proxy.AdminHandler())))
+		}()
+	}
+	log.Fatal(http.ListenAndServe(*flagHttpAddr, Log(proxy)))
+}
+
+func Log(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s %s", r.RemoteAddr, r.Method, r.URL.Path)
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sdgoij/gobbb"
+)
+
+const APIPath = "/bigbluebutton/api/"
+
+// DefaultMaxBodySize limits the create and insertDocument bodies, which are
+// buffered to be sent again if a server fails.
+const DefaultMaxBodySize = 64 << 20
+
+// Proxy serves the BigBlueButton API under its own secret and spreads the
+// meetings over the servers of a bbb.Pool. Calls on a meeting are forwarded
+// to the server hosting it, signed with that server's secret, and answered
+// with the server's response; getMeetings and getRecordings are merged from
+// all servers.
+type Proxy struct {
+	Secret string
+
+	// MaxBodySize limits request bodies; DefaultMaxBodySize if 0.
+	MaxBodySize int64
+
+	pool    *bbb.Pool
+	m       sync.RWMutex
+	servers map[string]*bbb.BigBlueButton
+}
+
+func NewProxy(secret string) *Proxy {
+	return &Proxy{
+		Secret:  secret,
+		pool:    bbb.NewPool(),
+		servers: map[string]*bbb.BigBlueButton{},
+	}
+}
+
+// Add adds a server under a unique name.
+func (p *Proxy) Add(name string, b3 *bbb.BigBlueButton) error {
+	if err := p.pool.Add(name, b3); nil != err {
+		return err
+	}
+	p.m.Lock()
+	p.servers[name] = b3
+	p.m.Unlock()
+	return nil
+}
+
+// Pool returns the pool of servers, e.g. to refresh it periodically.
+func (p *Proxy) Pool() *bbb.Pool {
+	return p.pool
+}
+
+func (p *Proxy) server(name string) *bbb.BigBlueButton {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	return p.servers[name]
+}
+
+type handlerFunc func(*Proxy, http.ResponseWriter, *http.Request, url.Values)
+
+var handlers = map[string]handlerFunc{
+	"create":            handleCreate,
+	"join":              handleJoin,
+	"isMeetingRunning":  handleIsMeetingRunning,
+	"end":               handleEnd,
+	"insertDocument":    handleForward,
+	"getMeetingInfo":    handleForward,
+	"getMeetings":       handleMeetings,
+	"getRecordings":     handleRecordings,
+	"publishRecordings": handlePublishRecordings,
+	"deleteRecordings":  handleDeleteRecordings,
+	"updateRecordings":  handleUpdateRecordings,
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if APIPath == req.URL.Path+"/" {
+		handleVersion(p, w, req)
+		return
+	}
+	if !strings.HasPrefix(req.URL.Path, APIPath) {
+		http.NotFound(w, req)
+		return
+	}
+	action := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, APIPath), ".xml")
+	if "" == action {
+		handleVersion(p, w, req)
+		return
+	}
+	h, t := handlers[action]
+	if !t {
+		writeXML(w, 0, failed("unsupportedRequest", "This request is not supported."))
+		return
+	}
+	params, _, err := bbb.VerifyChecksum(action, req.URL.RawQuery, p.Secret)
+	if nil != err {
+		writeXML(w, 0, failed("checksumError", "You did not pass the checksum security check"))
+		return
+	}
+	h(p, w, req, params)
+}
+
+func handleVersion(p *Proxy, w http.ResponseWriter, req *http.Request) {
+	version, err := p.pool.ServerVersionWithContext(req.Context())
+	if nil != err {
+		writeError(w, err)
+		return
+	}
+	writeXML(w, 0, &versionResponse{status: success(), Version: version})
+}
+
+// handleCreate forwards create to the server already hosting the meeting,
+// or to the least loaded one, trying the next if a server fails.
+func handleCreate(p *Proxy, w http.ResponseWriter, req *http.Request, params url.Values) {
+	id := params.Get("meetingID")
+	if "" == id {
+		writeXML(w, 0, failed("missingParamMeetingID", "You must specify a meeting ID for the meeting."))
+		return
+	}
+	body, ok := p.body(w, req)
+	if !ok {
+		return
+	}
+	// res is the response of the last server tried, which the error
+	// returned by Assign comes from; nil if it could not be reached.
+	var res *response
+	_, err := p.pool.Assign(req.Context(), id, func(n bbb.PoolNode) error {
+		r, err := p.do(req.Context(), n.Name, "create", params, req.Header.Get("Content-Type"), body)
+		if res = r; nil != err {
+			return err
+		}
+		return r.err("create")
+	})
+	if nil != res {
+		res.write(w)
+		return
+	}
+	writeError(w, err)
+}
+
+// handleJoin redirects to the join URL on the server hosting the meeting.
+func handleJoin(p *Proxy, w http.ResponseWriter, req *http.Request, params url.Values) {
+	n, err := p.pool.Lookup(req.Context(), params.Get("meetingID"))
+	if nil != err {
+		writeError(w, err)
+		return
+	}
+	http.Redirect(w, req, p.server(n.Name).SignedURL("join", params).String(), http.StatusFound)
+}
+
+func handleIsMeetingRunning(p *Proxy, w http.ResponseWriter, req *http.Request, params url.Values) {
+	running, err := p.pool.IsMeetingRunningWithContext(req.Context(), params.Get("meetingID"))
+	if nil != err {
+		writeError(w, err)
+		return
+	}
+	writeXML(w, 0, &runningResponse{status: success(), Running: running})
+}
+
+// handleForward forwards a call on a meeting to the server hosting it.
+func handleForward(p *Proxy, w http.ResponseWriter, req *http.Request, params url.Values) {
+	action := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, APIPath), ".xml")
+	n, err := p.pool.Lookup(req.Context(), params.Get("meetingID"))
+	if nil != err {
+		writeError(w, err)
+		return
+	}
+	body, ok := p.body(w, req)
+	if !ok {
+		return
+	}
+	res, err := p.do(req.Context(), n.Name, action, params, req.Header.Get("Content-Type"), body)
+	if nil != err {
+		writeError(w, err)
+		return
+	}
+	res.write(w)
+}
+
+// handleEnd forwards end and makes the pool forget the meeting once the
+// server accepted it, so that it can be created anew on an active server.
+func handleEnd(p *Proxy, w http.ResponseWriter, req *http.Request, params url.Values) {
+	id := params.Get("meetingID")
+	n, err := p.pool.Lookup(req.Context(), id)
+	if nil != err {
+		writeError(w, err)
+		return
+	}
+	res, err := p.do(req.Context(), n.Name, "end", params, "", nil)
+	if nil != err {
+		writeError(w, err)
+		return
+	}
+	if err := res.err("end"); nil == err || bbb.IsNotFound(err) {
+		p.pool.Forget(id)
+	}
+	res.write(w)
+}
+
+func handleMeetings(p *Proxy, w http.ResponseWriter, req *http.Request, params url.Values) {
+	meetings, err := p.meetings(req.Context(), params)
+	if nil != err {
+		writeError(w, err)
+		return
+	}
+	response := &meetingsResponse{status: success()}
+	response.Meetings.List = meetings
+	if 0 == len(meetings) {
+		response.MessageKey = "noMeetings"
+		response.Message = "no meetings were found on this server"
+	}
+	writeXML(w, 0, response)
+}
+
+// handleRecordings answers with the recordings of all servers as merged by
+// the pool, paged if a limit is given.
+func handleRecordings(p *Proxy, w http.ResponseWriter, req *http.Request, params url.Values) {
+	options := &bbb.RecordingsOptions{
+		MeetingIds: splitParam(params.Get("meetingID")),
+		RecordIds:  splitParam(params.Get("recordID")),
+		States:     splitParam(params.Get("state")),
+		Metadata:   metaParams(params),
+	}
+	_, paged := params["limit"]
+	if paged {
+		limit, _ := strconv.Atoi(params.Get("limit"))
+		offset, _ := strconv.Atoi(params.Get("offset"))
+		if limit < 1 || limit > 100 || offset < 0 {
+			writeXML(w, 0, failed("invalidParameters", "The offset and limit parameters are invalid."))
+			return
+		}
+		options.Offset, options.Limit = uint(offset), uint(limit)
+	}
+	page, err := p.pool.RecordingsPageWithContext(req.Context(), options)
+	if nil != err {
+		writeError(w, err)
+		return
+	}
+	response := &recordingsResponse{status: success()}
+	response.Recordings.List = page.Recordings
+	if paged {
+		response.Total = &page.Total
+	}
+	if 0 == len(page.Recordings) {
+		response.MessageKey = "noRecordings"
+		response.Message = "There are no recordings for the meeting(s)."
+	}
+	writeXML(w, 0, response)
+}
+
+func handlePublishRecordings(p *Proxy, w http.ResponseWriter, req *http.Request, params url.Values) {
+	ok, err := p.pool.PublishRecordingsWithContext(req.Context(), splitParam(params.Get("recordID")),
+		"true" == params.Get("publish"))
+	if nil != err {
+		writeError(w, err)
+		return
+	}
+	writeXML(w, 0, &publishResponse{status: success(), Published: ok})
+}
+
+func handleDeleteRecordings(p *Proxy, w http.ResponseWriter, req *http.Request, params url.Values) {
+	ok, err := p.pool.DeleteRecordingsWithContext(req.Context(), splitParam(params.Get("recordID")))
+	if nil != err {
+		writeError(w, err)
+		return
+	}
+	writeXML(w, 0, &deleteResponse{status: success(), Deleted: ok})
+}
+
+func handleUpdateRecordings(p *Proxy, w http.ResponseWriter, req *http.Request, params url.Values) {
+	results, err := p.pool.UpdateRecordingsWithContext(req.Context(), splitParam(params.Get("recordID")), metaParams(params))
+	if nil == err {
+		for _, id := range splitParam(params.Get("recordID")) {
+			if err = results[id]; nil != err {
+				break
+			}
+		}
+	}
+	if nil != err {
+		writeError(w, err)
+		return
+	}
+	writeXML(w, 0, &updateResponse{status: success(), Updated: true})
+}
+
+// body reads the request body, if any, answering with an error if it is too
+// large.
+func (p *Proxy) body(w http.ResponseWriter, req *http.Request) ([]byte, bool) {
+	if "POST" != req.Method || nil == req.Body {
+		return nil, true
+	}
+	max := p.MaxBodySize
+	if max <= 0 {
+		max = DefaultMaxBodySize
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(req.Body, max+1))
+	if nil != err {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if int64(len(data)) > max {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+	return data, true
+}
+
+// do sends the call to the named server, signed with its secret. The
+// response is read completely; server errors are returned as *bbb.APIError.
+func (p *Proxy) do(ctx context.Context, name, action string, params url.Values, contentType string, body []byte) (*response, error) {
+	b3 := p.server(name)
+	method, reader := "GET", io.Reader(nil)
+	if nil != body {
+		method, reader = "POST", bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, b3.SignedURL(action, params).String(), reader)
+	if nil != err {
+		return nil, err
+	}
+	if nil != body {
+		req.Header.Set("Content-Type", contentType)
+	}
+	client := b3.Client
+	if nil == client {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req.WithContext(ctx))
+	if nil != err {
+		return nil, err
+	}
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if nil != err {
+		return nil, err
+	}
+	if res.StatusCode >= 500 {
+		return nil, &bbb.APIError{Action: action, Message: http.StatusText(res.StatusCode), StatusCode: res.StatusCode}
+	}
+	return &response{res.StatusCode, res.Header.Get("Content-Type"), data}, nil
+}
+
+// meetings runs getMeetings on every enabled server concurrently and
+// returns the meetings as sent by the servers. It fails if any server does,
+// as the list would be incomplete otherwise.
+func (p *Proxy) meetings(ctx context.Context, params url.Values) ([]rawXML, error) {
+	nodes := []bbb.PoolNode{}
+	for _, n := range p.pool.Nodes() {
+		if bbb.NodeDisabled != n.State {
+			nodes = append(nodes, n)
+		}
+	}
+	if 0 == len(nodes) {
+		return nil, bbb.ErrNoHealthyNode
+	}
+	lists, errs := make([]meetingsResponse, len(nodes)), make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for k, n := range nodes {
+		wg.Add(1)
+		go func(k int, name string) {
+			defer wg.Done()
+			res, err := p.do(ctx, name, "getMeetings", params, "", nil)
+			if nil == err {
+				if err = res.err("getMeetings"); nil == err {
+					err = xml.Unmarshal(res.body, &lists[k])
+				}
+			}
+			errs[k] = err
+		}(k, n.Name)
+	}
+	wg.Wait()
+	meetings := []rawXML{}
+	for k, err := range errs {
+		if nil != err {
+			return nil, err
+		}
+		meetings = append(meetings, lists[k].Meetings.List...)
+	}
+	return meetings, nil
+}
+
+// metaParams returns the meta_ parameters without their prefix.
+func metaParams(params url.Values) map[string]string {
+	meta := map[string]string{}
+	for k := range params {
+		if strings.HasPrefix(k, "meta_") {
+			meta[strings.TrimPrefix(k, "meta_")] = params.Get(k)
+		}
+	}
+	return meta
+}
+
+func splitParam(v string) (list []string) {
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); "" != s {
+			list = append(list, s)
+		}
+	}
+	return
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sdgoij/gobbb"
+	"github.com/sdgoij/gobbb/bbbtest"
+)
+
+func newTestProxy(t *testing.T, n int) (*Proxy, []*bbbtest.Server, *bbb.BigBlueButton) {
+	proxy := NewProxy("proxy-secret")
+	servers := make([]*bbbtest.Server, n)
+	for k := range servers {
+		servers[k] = bbbtest.NewServer(fmt.Sprintf("secret%d", k))
+		t.Cleanup(servers[k].Close)
+		proxy.Add(fmt.Sprintf("node%d", k), servers[k].NewClient())
+	}
+	ts := httptest.NewServer(proxy)
+	t.Cleanup(ts.Close)
+	b3, err := bbb.New(ts.URL+APIPath, "proxy-secret")
+	if nil != err {
+		t.Fatal(err)
+	}
+	return proxy, servers, &b3
+}
+
+func TestProxyMeetings(t *testing.T) {
+	_, servers, b3 := newTestProxy(t, 2)
+	for k, id := range []string{"a", "b"} {
+		if _, err := b3.Create(id, &bbb.CreateOptions{Name: id}); nil != err {
+			t.Fatal(err)
+		}
+		if _, found := servers[k].Meeting(id); !found {
+			t.Errorf("meeting %s not created on node%d", id, k)
+		}
+	}
+	servers[0].Fail("create", &bbbtest.Failure{StatusCode: http.StatusServiceUnavailable})
+	if _, err := b3.Create("c", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	if _, found := servers[1].Meeting("c"); !found {
+		t.Error("meeting c not created on node1")
+	}
+	servers[0].Fail("create", nil)
+
+	m, err := b3.MeetingInfo("b", "")
+	if nil != err || "b" != m.Name {
+		t.Errorf("unexpected meeting info: %v (%v)", m, err)
+	}
+	if _, err := b3.MeetingInfo("nope", ""); !bbb.IsNotFound(err) {
+		t.Errorf("expected notFound, got %v", err)
+	}
+	if running, err := b3.IsMeetingRunning("nope"); nil != err || running {
+		t.Errorf("unexpected running state: %v (%v)", running, err)
+	}
+	if meetings, err := b3.Meetings(); nil != err || 3 != len(meetings) {
+		t.Errorf("unexpected meetings: %v (%v)", meetings, err)
+	}
+	servers[0].Fail("getMeetings", &bbbtest.Failure{StatusCode: http.StatusServiceUnavailable, Times: 1})
+	if meetings, err := b3.Meetings(); nil == err {
+		t.Errorf("expected error for failing node0, got %v", meetings)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	res, err := client.Get(b3.JoinURL("Alice", "b", m.ModeratorPW, bbb.EmptyOptions))
+	if nil != err {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if location := res.Header.Get("Location"); !strings.HasPrefix(location, servers[1].URL) {
+		t.Fatalf("unexpected join redirect: %d %s", res.StatusCode, location)
+	}
+	if res, err = http.Get(res.Header.Get("Location")); nil != err {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if m, _ := servers[1].Meeting("b"); 1 != len(m.Attendees) || bbb.RoleModerator != m.Attendees[0].Role {
+		t.Errorf("join not forwarded: %#v", m.Attendees)
+	}
+
+	if err := b3.End("a", "", nil); nil != err {
+		t.Fatal(err)
+	}
+	if _, found := servers[0].Meeting("a"); found {
+		t.Error("meeting a not ended")
+	}
+
+	wrong := *b3
+	wrong.Secret = "secret0"
+	if _, err := wrong.Meetings(); !bbb.IsChecksumError(err) {
+		t.Errorf("expected checksumError, got %v", err)
+	}
+	wrong.Secret, wrong.Checksum = "proxy-secret", bbb.SHA512
+	if _, err := wrong.Meetings(); nil != err {
+		t.Errorf("SHA512 checksum rejected: %v", err)
+	}
+}
+
+func TestProxyRecordings(t *testing.T) {
+	_, servers, b3 := newTestProxy(t, 2)
+	for k, server := range servers {
+		for i := 0; i < 2; i++ {
+			server.AddRecording(bbb.Recording{
+				RecordId:  fmt.Sprintf("r%d%d", k, i),
+				MeetingId: "a",
+				StartTime: time.Unix(int64(1530718721+2*i+k), 0),
+				Metadata:  map[string]string{"course": fmt.Sprintf("cs%d", i)},
+				Playback:  []bbb.Playback{{Type: "presentation", Url: "http://example.com/playback"}},
+			})
+		}
+	}
+	recordings, err := b3.Recordings(nil)
+	if nil != err || 4 != len(recordings) || "r00" != recordings[0].RecordId || "r10" != recordings[1].RecordId {
+		t.Fatalf("unexpected recordings: %v (%v)", recordings, err)
+	}
+	if f := recordings[0].Format("presentation"); nil == f || "http://example.com/playback" != f.Url || "cs0" != recordings[0].Metadata["course"] {
+		t.Errorf("unexpected recording: %#v", recordings[0])
+	}
+	page, err := b3.RecordingsPage(&bbb.RecordingsOptions{Metadata: map[string]string{"course": "cs1"}, Limit: 10})
+	if nil != err || 2 != page.Total || "r01" != page.Recordings[0].RecordId {
+		t.Errorf("unexpected metadata page: %v (%v)", page, err)
+	}
+	page, err = b3.RecordingsPage(&bbb.RecordingsOptions{Offset: 1, Limit: 2})
+	if nil != err || 4 != page.Total || 2 != len(page.Recordings) || "r10" != page.Recordings[0].RecordId {
+		t.Errorf("unexpected page: %v (%v)", page, err)
+	}
+	servers[1].Fail("getRecordings", &bbbtest.Failure{StatusCode: http.StatusServiceUnavailable, Times: 1})
+	if page, err := b3.RecordingsPage(&bbb.RecordingsOptions{Limit: 2}); nil == err {
+		t.Errorf("expected error for failing node1, got %v", page)
+	}
+
+	if ok, err := b3.PublishRecordings([]string{"r01", "r11"}, true); nil != err || !ok {
+		t.Errorf("expected published, got %v (%v)", ok, err)
+	}
+	for k, server := range servers {
+		if r, _ := server.Recording(fmt.Sprintf("r%d1", k)); !r.Published {
+			t.Errorf("recording not published on node%d: %#v", k, r)
+		}
+	}
+	if results, err := b3.UpdateRecordings([]string{"r00"}, map[string]string{"name": "Zero"}); nil != err || nil != results["r00"] {
+		t.Errorf("unexpected update: %v (%v)", results, err)
+	}
+	if r, _ := servers[0].Recording("r00"); "Zero" != r.Metadata["name"] {
+		t.Errorf("recording not updated: %#v", r.Metadata)
+	}
+	if ok, err := b3.DeleteRecordings([]string{"r10"}); nil != err || !ok {
+		t.Errorf("expected deleted, got %v (%v)", ok, err)
+	}
+	if _, err := b3.DeleteRecordings([]string{"missing"}); !bbb.IsNotFound(err) {
+		t.Errorf("expected notFound, got %v", err)
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	proxy, servers, b3 := newTestProxy(t, 2)
+	admin := httptest.NewServer(proxy.AdminHandler())
+	defer admin.Close()
+	state := func(name string) (s serverJSON) {
+		t.Helper()
+		res, err := http.Get(admin.URL + AdminPath + name)
+		if nil != err {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if err := json.NewDecoder(res.Body).Decode(&s); nil != err {
+			t.Fatal(err)
+		}
+		return
+	}
+	set := func(name, state string) int {
+		t.Helper()
+		res, err := http.PostForm(admin.URL+AdminPath+name, url.Values{"state": {state}})
+		if nil != err {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	for _, id := range []string{"a", "b"} {
+		if _, err := b3.Create(id, bbb.EmptyOptions); nil != err {
+			t.Fatal(err)
+		}
+	}
+	servers[0].AddAttendee("a", bbb.Attendee{UserId: "1", Name: "Alice"})
+	if code := set("node0", "draining"); http.StatusOK != code {
+		t.Fatalf("unexpected status %d", code)
+	}
+	if _, err := b3.Create("c", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	if _, found := servers[1].Meeting("c"); !found {
+		t.Error("meeting c placed on draining node0")
+	}
+	if m, err := b3.MeetingInfo("a", ""); nil != err || 1 != m.NumUsers {
+		t.Errorf("meeting a not routed to draining node0: %v (%v)", m, err)
+	}
+
+	ctx := context.Background()
+	proxy.Pool().Refresh(ctx)
+	if s := state("node0"); "draining" != s.State || 1 != s.Running || s.SafeToShutDown {
+		t.Errorf("unexpected node0 state: %#v", s)
+	}
+	if err := b3.End("a", "", nil); nil != err {
+		t.Fatal(err)
+	}
+	if _, err := b3.Create("a", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	if _, found := servers[1].Meeting("a"); !found {
+		t.Error("meeting a created again on draining node0")
+	}
+	proxy.Pool().Refresh(ctx)
+	if s := state("node0"); 0 != s.Running || !s.SafeToShutDown {
+		t.Errorf("expected node0 safe to shut down: %#v", s)
+	}
+
+	servers[1].AddAttendee("b", bbb.Attendee{UserId: "2", Name: "Bob"})
+	if code := set("node1", "disabled"); http.StatusOK != code {
+		t.Fatalf("unexpected status %d", code)
+	}
+	if _, err := b3.Create("d", bbb.EmptyOptions); nil == err {
+		t.Error("expected create to fail without active nodes")
+	}
+	m, err := b3.MeetingInfo("b", "")
+	if nil != err || 1 != m.NumUsers {
+		t.Errorf("meeting b not routed to disabled node1: %v (%v)", m, err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	res, err := client.Get(b3.JoinURL("Carol", "b", m.AttendeePW, bbb.EmptyOptions))
+	if nil != err {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if location := res.Header.Get("Location"); !strings.HasPrefix(location, servers[1].URL) {
+		t.Errorf("unexpected join redirect: %d %s", res.StatusCode, location)
+	}
+	proxy.Pool().Refresh(ctx)
+	if s := state("node1"); "disabled" != s.State || 1 != s.Running || s.SafeToShutDown {
+		t.Errorf("unexpected node1 state: %#v", s)
+	}
+	if err := b3.End("b", "", nil); nil != err {
+		t.Fatal(err)
+	}
+	proxy.Pool().Refresh(ctx)
+	if s := state("node1"); !s.SafeToShutDown {
+		t.Errorf("expected node1 safe to shut down: %#v", s)
+	}
+	set("node0", "active")
+	set("node1", "active")
+	if _, err := b3.Create("d", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+
+	if code := set("node0", "paused"); http.StatusBadRequest != code {
+		t.Errorf("expected %d for invalid state, got %d", http.StatusBadRequest, code)
+	}
+	if code := set("node9", "active"); http.StatusNotFound != code {
+		t.Errorf("expected %d for unknown node, got %d", http.StatusNotFound, code)
+	}
+}
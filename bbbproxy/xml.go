@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/sdgoij/gobbb"
+)
+
+type status struct {
+	ReturnCode string `xml:"returncode"`
+	MessageKey string `xml:"messageKey,omitempty"`
+	Message    string `xml:"message,omitempty"`
+}
+
+func success() status {
+	return status{ReturnCode: "SUCCESS"}
+}
+
+type statusResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+}
+
+func failed(key, message string) *statusResponse {
+	return &statusResponse{status: status{"FAILED", key, message}}
+}
+
+type versionResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Version string `xml:"version"`
+}
+
+type runningResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Running bool `xml:"running"`
+}
+
+type publishResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Published bool `xml:"published"`
+}
+
+type deleteResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Deleted bool `xml:"deleted"`
+}
+
+type updateResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Updated bool `xml:"updated"`
+}
+
+type meetingsResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Meetings struct {
+		List []rawXML `xml:"meeting"`
+	} `xml:"meetings"`
+}
+
+type recordingsResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Recordings struct {
+		List []*bbb.Recording `xml:"recording"`
+	} `xml:"recordings"`
+	Total *int `xml:"totalElements,omitempty"`
+}
+
+// rawXML is an element copied verbatim from a server response.
+type rawXML struct {
+	XMLName xml.Name
+	Inner   []byte `xml:",innerxml"`
+}
+
+// response is a server response, read completely.
+type response struct {
+	code        int
+	contentType string
+	body        []byte
+}
+
+func (r *response) write(w http.ResponseWriter) {
+	if "" != r.contentType {
+		w.Header().Set("Content-Type", r.contentType)
+	}
+	w.WriteHeader(r.code)
+	w.Write(r.body)
+}
+
+// err returns the error reported by the response, if any.
+func (r *response) err(action string) error {
+	return bbb.CheckResponse(&http.Response{StatusCode: r.code, Body: ioutil.NopCloser(bytes.NewReader(r.body))}, action)
+}
+
+func writeXML(w http.ResponseWriter, code int, v interface{}) {
+	data, err := xml.Marshal(v)
+	if nil != err {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if 0 == code {
+		code = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "text/xml;charset=UTF-8")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// writeError answers with the error a server reported, or with an
+// internalError if no server could be reached.
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *bbb.APIError
+	if errors.As(err, &apiErr) && "" != apiErr.ReturnCode {
+		writeXML(w, 0, failed(apiErr.MessageKey, apiErr.Message))
+		return
+	}
+	log.Println("bbbproxy:", err)
+	writeXML(w, http.StatusBadGateway, failed("internalError", "No server could handle the request."))
+}
@@ -0,0 +1,598 @@
+package bbbtest
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sdgoij/gobbb"
+)
+
+type handlerFunc func(*Server, http.ResponseWriter, *http.Request, url.Values)
+
+var handlers = map[string]handlerFunc{
+	"create":                 handleCreate,
+	"join":                   handleJoin,
+	"isMeetingRunning":       handleIsMeetingRunning,
+	"end":                    handleEnd,
+	"insertDocument":         handleInsertDocument,
+	"getMeetingInfo":         handleMeetingInfo,
+	"getMeetings":            handleMeetings,
+	"getRecordings":          handleRecordings,
+	"publishRecordings":      handlePublishRecordings,
+	"deleteRecordings":       handleDeleteRecordings,
+	"updateRecordings":       handleUpdateRecordings,
+	"getRecordingTextTracks": handleRecordingTextTracks,
+	"putRecordingTextTrack":  handlePutRecordingTextTrack,
+	"getDefaultConfigXML":    handleDefaultConfigXML,
+	"setConfigXML":           handleSetConfigXML,
+	"hooks/create":           handleHooksCreate,
+	"hooks/list":             handleHooksList,
+	"hooks/destroy":          handleHooksDestroy,
+}
+
+func handleCreate(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	id := params.Get("meetingID")
+	if "" == id {
+		writeXML(w, 0, failed("missingParamMeetingID", "You must specify a meeting ID for the meeting."))
+		return
+	}
+	if m, t := s.meetings[id]; t {
+		if pw := params.Get("attendeePW"); "" != pw && pw != m.AttendeePW {
+			writeXML(w, 0, failed("idNotUnique", "A meeting already exists with that meeting ID."))
+			return
+		}
+		if pw := params.Get("moderatorPW"); "" != pw && pw != m.ModeratorPW {
+			writeXML(w, 0, failed("idNotUnique", "A meeting already exists with that meeting ID."))
+			return
+		}
+		r := createResponse(m)
+		r.MessageKey = "duplicateWarning"
+		r.Message = "This conference was already in existence and may currently be in progress."
+		writeXML(w, 0, r)
+		return
+	}
+	now := time.Now()
+	m := &meeting{sessions: map[string]string{}}
+	m.Id = id
+	m.InternalId = fmt.Sprintf("%x-%d", sha1.Sum([]byte(id)), now.UnixNano()/1e6)
+	m.Name = params.Get("name")
+	if "" == m.Name {
+		m.Name = id
+	}
+	m.CreateTime = now
+	m.AttendeePW = params.Get("attendeePW")
+	if "" == m.AttendeePW {
+		m.AttendeePW = s.nextId("ap")
+	}
+	m.ModeratorPW = params.Get("moderatorPW")
+	if "" == m.ModeratorPW {
+		m.ModeratorPW = s.nextId("mp")
+	}
+	m.VoiceBridge, _ = strconv.Atoi(params.Get("voiceBridge"))
+	if 0 == m.VoiceBridge {
+		m.VoiceBridge = 70000 + s.sequence
+	}
+	m.MaxUsers, _ = strconv.Atoi(params.Get("maxParticipants"))
+	m.Recording = "true" == params.Get("record")
+	m.Metadata = map[string]string{}
+	for k := range params {
+		if strings.HasPrefix(k, "meta_") {
+			m.Metadata[strings.ToLower(k[5:])] = params.Get(k)
+		}
+	}
+	if "true" == params.Get("isBreakout") {
+		parent := s.meetingByInternalId(params.Get("parentMeetingID"))
+		if nil == parent {
+			writeXML(w, 0, failed("parentMeetingDoesNotExist", "No parent meeting exists for the breakout room."))
+			return
+		}
+		m.IsBreakout = true
+		m.ParentMeetingID = parent.InternalId
+		m.Sequence, _ = strconv.Atoi(params.Get("sequence"))
+		m.FreeJoin = "true" == params.Get("freeJoin")
+		parent.BreakoutRooms = append(parent.BreakoutRooms, m.InternalId)
+	}
+	if "POST" == req.Method {
+		docs, err := readDocuments(req.Body)
+		if nil != err {
+			writeXML(w, 0, failed("invalidDocument", err.Error()))
+			return
+		}
+		m.documents = docs
+	}
+	s.meetings[id] = m
+	writeXML(w, 0, createResponse(m))
+}
+
+func handleJoin(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	fail := func(key, message string) {
+		if u := params.Get("errorRedirectUrl"); "" != u {
+			errors, _ := json.Marshal([]map[string]string{{"key": key, "message": message}})
+			http.Redirect(w, req, u+"?"+url.Values{"errors": {string(errors)}}.Encode(), http.StatusFound)
+			return
+		}
+		writeXML(w, 0, failed(key, message))
+	}
+	m, t := s.meetings[params.Get("meetingID")]
+	if !t {
+		fail("invalidMeetingIdentifier", "The meeting ID that you supplied did not match any existing meetings")
+		return
+	}
+	a := bbb.Attendee{
+		UserId:     params.Get("userID"),
+		Name:       params.Get("fullName"),
+		ClientType: "HTML5",
+		CustomData: map[string]string{},
+	}
+	for k := range params {
+		if strings.HasPrefix(k, "userdata-") {
+			a.CustomData[k[9:]] = params.Get(k)
+		}
+	}
+	switch role, password := strings.ToUpper(params.Get("role")), params.Get("password"); {
+	case bbb.RoleModerator == role || bbb.RoleViewer == role:
+		a.Role = role
+	case "" != role:
+		fail("invalidRole", "The role that you supplied is invalid.")
+		return
+	case m.ModeratorPW == password:
+		a.Role = bbb.RoleModerator
+	case m.AttendeePW == password:
+		a.Role = bbb.RoleViewer
+	default:
+		fail("invalidPassword", "You either did not supply a password or the password supplied is neither the attendee or moderator password for this conference.")
+		return
+	}
+	if "" == a.Name {
+		fail("missingParamFullName", "You must specify a name for the attendee who will be joining the meeting.")
+		return
+	}
+	a = s.join(m, a)
+	token := s.nextId("session")
+	m.sessions[token] = a.UserId
+	u := s.URL + "/html5client/join?sessionToken=" + token
+	if "false" == params.Get("redirect") {
+		writeXML(w, 0, &joinResponse{
+			status:       success(),
+			MeetingId:    m.InternalId,
+			UserId:       a.UserId,
+			AuthToken:    token,
+			SessionToken: token,
+			Url:          u,
+		})
+		return
+	}
+	http.Redirect(w, req, u, http.StatusFound)
+}
+
+func handleIsMeetingRunning(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	m, t := s.meetings[params.Get("meetingID")]
+	writeXML(w, 0, &runningResponse{status: success(), Running: t && len(m.Attendees) > 0})
+}
+
+func handleEnd(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	m, t := s.meetings[params.Get("meetingID")]
+	if !t {
+		writeXML(w, 0, failed("notFound", "We could not find a meeting with that meeting ID - perhaps the meeting is not yet running?"))
+		return
+	}
+	if pw := params.Get("password"); "" != pw && pw != m.ModeratorPW {
+		writeXML(w, 0, failed("invalidPassword", "You must supply the moderator password for this call."))
+		return
+	}
+	s.end(m)
+	r := success()
+	r.MessageKey = "sentEndMeetingRequest"
+	r.Message = "A request to end the meeting was sent. Please wait a few seconds, and then use the getMeetingInfo or isMeetingRunning API calls to verify that it was ended."
+	writeXML(w, 0, &statusResponse{status: r})
+}
+
+func handleInsertDocument(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	m, t := s.meetings[params.Get("meetingID")]
+	if !t {
+		writeXML(w, 0, failed("notFound", "We could not find a meeting with that meeting ID"))
+		return
+	}
+	docs, err := readDocuments(req.Body)
+	if nil != err || 0 == len(docs) {
+		writeXML(w, 0, failed("invalidDocument", "No valid document was sent."))
+		return
+	}
+	m.documents = append(m.documents, docs...)
+	r := success()
+	r.Message = "Presentation is being uploaded"
+	writeXML(w, 0, &statusResponse{status: r})
+}
+
+func handleMeetingInfo(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	m, t := s.meetings[params.Get("meetingID")]
+	if !t {
+		writeXML(w, 0, failed("notFound", "We could not find a meeting with that meeting ID"))
+		return
+	}
+	writeXML(w, 0, &meetingInfoResponse{status: success(), meetingXML: s.meetingXML(m)})
+}
+
+func handleMeetings(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	r := &meetingsResponse{status: success()}
+	for _, m := range s.sortedMeetings() {
+		r.Meetings = append(r.Meetings, s.meetingXML(m))
+	}
+	if len(r.Meetings) < 1 {
+		r.MessageKey = "noMeetings"
+		r.Message = "no meetings were found on this server"
+	}
+	writeXML(w, 0, r)
+}
+
+func handleRecordings(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	meetings := splitParam(params.Get("meetingID"))
+	records := splitParam(params.Get("recordID"))
+	states := splitParam(params.Get("state"))
+	if 0 == len(states) {
+		states = []string{"published", "unpublished"}
+	}
+	var list []*bbb.Recording
+	for _, r := range s.recordings {
+		if (len(meetings) > 0 && !contains(meetings, r.MeetingId)) ||
+			(len(records) > 0 && !contains(records, r.RecordId)) ||
+			(!contains(states, "any") && !contains(states, r.State)) ||
+			!matchesMeta(r.Metadata, params) {
+			continue
+		}
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StartTime.Before(list[j].StartTime) ||
+			(list[i].StartTime.Equal(list[j].StartTime) && list[i].RecordId < list[j].RecordId)
+	})
+	response := &recordingsResponse{status: success()}
+	if _, t := params["limit"]; t {
+		limit, _ := strconv.Atoi(params.Get("limit"))
+		offset, _ := strconv.Atoi(params.Get("offset"))
+		if limit < 1 || limit > 100 || offset < 0 {
+			writeXML(w, 0, failed("invalidParameters", "The offset and limit parameters are invalid."))
+			return
+		}
+		total := len(list)
+		response.Total = &total
+		if offset > len(list) {
+			offset = len(list)
+		}
+		if list = list[offset:]; len(list) > limit {
+			list = list[:limit]
+		}
+	}
+	for _, r := range list {
+		response.Recordings = append(response.Recordings, r)
+	}
+	if len(list) < 1 {
+		response.MessageKey = "noRecordings"
+		response.Message = "There are no recordings for the meeting(s)."
+	}
+	writeXML(w, 0, response)
+}
+
+// matchesMeta reports whether meta has all meta_* values of params.
+func matchesMeta(meta map[string]string, params url.Values) bool {
+	for k := range params {
+		if strings.HasPrefix(k, "meta_") && meta[strings.ToLower(k[5:])] != params.Get(k) {
+			return false
+		}
+	}
+	return true
+}
+
+func handlePublishRecordings(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	publish := params.Get("publish")
+	if "true" != publish && "false" != publish {
+		writeXML(w, 0, failed("missingParamPublish", "You must specify a publish value true or false."))
+		return
+	}
+	ids, ok := s.recordingIds(w, params)
+	if !ok {
+		return
+	}
+	for _, id := range ids {
+		r := s.recordings[id]
+		r.Published = "true" == publish
+		if r.State = "unpublished"; r.Published {
+			r.State = "published"
+		}
+	}
+	writeXML(w, 0, &publishResponse{status: success(), Published: "true" == publish})
+}
+
+func handleDeleteRecordings(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	ids, ok := s.recordingIds(w, params)
+	if !ok {
+		return
+	}
+	for _, id := range ids {
+		delete(s.recordings, id)
+		delete(s.tracks, id)
+	}
+	writeXML(w, 0, &deleteResponse{status: success(), Deleted: true})
+}
+
+func handleUpdateRecordings(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	ids, ok := s.recordingIds(w, params)
+	if !ok {
+		return
+	}
+	for _, id := range ids {
+		r := s.recordings[id]
+		for k := range params {
+			if strings.HasPrefix(k, "meta_") {
+				if v := params.Get(k); "" == v {
+					delete(r.Metadata, strings.ToLower(k[5:]))
+				} else {
+					r.Metadata[strings.ToLower(k[5:])] = v
+				}
+			}
+		}
+	}
+	writeXML(w, 0, &updateResponse{status: success(), Updated: true})
+}
+
+func handleRecordingTextTracks(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	id := params.Get("recordID")
+	if "" == id {
+		writeJSON(w, jsonFailed("missingParameter", "param recordID is missing"))
+		return
+	}
+	if _, t := s.recordings[id]; !t {
+		writeJSON(w, jsonFailed("noRecordings", "No recording found for "+id))
+		return
+	}
+	tracks := append([]bbb.TextTrack{}, s.tracks[id]...)
+	writeJSON(w, &jsonResponse{ReturnCode: "SUCCESS", Tracks: tracks})
+}
+
+func handlePutRecordingTextTrack(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	id, kind, lang := params.Get("recordID"), params.Get("kind"), params.Get("lang")
+	if _, t := s.recordings[id]; !t {
+		writeJSON(w, jsonFailed("noRecordings", "No recording found for "+id))
+		return
+	}
+	if "subtitles" != kind && "captions" != kind {
+		writeJSON(w, jsonFailed("invalidKind", "Invalid kind parameter, expected='subtitles|captions' actual="+kind))
+		return
+	}
+	if "" == lang {
+		writeJSON(w, jsonFailed("invalidLang", "Malformed lang param, received="))
+		return
+	}
+	file, _, err := req.FormFile("file")
+	if nil != err {
+		writeJSON(w, jsonFailed("empty_uploaded_text_track", "Empty uploaded text track."))
+		return
+	}
+	defer file.Close()
+	if data, _ := ioutil.ReadAll(file); len(data) < 1 {
+		writeJSON(w, jsonFailed("empty_uploaded_text_track", "Empty uploaded text track."))
+		return
+	}
+	label := params.Get("label")
+	if "" == label {
+		label = lang
+	}
+	tracks := []bbb.TextTrack{}
+	for _, track := range s.tracks[id] {
+		if track.Lang != lang || track.Kind != kind {
+			tracks = append(tracks, track)
+		}
+	}
+	s.tracks[id] = append(tracks, bbb.TextTrack{
+		Href:   s.URL + "/presentation/" + id + "/caption_" + lang + ".vtt",
+		Kind:   kind,
+		Label:  label,
+		Lang:   lang,
+		Source: "upload",
+	})
+	writeJSON(w, &jsonResponse{
+		ReturnCode: "SUCCESS",
+		MessageKey: "upload_text_track_success",
+		Message:    "Text track uploaded successfully",
+		RecordId:   id,
+	})
+}
+
+func handleDefaultConfigXML(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	c := &bbb.ConfigXML{
+		Version: s.Version,
+		Modules: []bbb.ConfigXML_Module{
+			{Name: "PresentModule", Url: "http://localhost/client/PresentModule.swf"},
+		},
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	config := strings.Replace(c.String(), "<ConfigXML>", "<config>", 1)
+	w.Write([]byte(strings.Replace(config, "</ConfigXML>", "</config>", 1)))
+}
+
+func handleSetConfigXML(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	if _, t := s.meetings[params.Get("meetingID")]; !t {
+		writeXML(w, 0, failed("notFound", "We could not find a meeting with that meeting ID"))
+		return
+	}
+	if "" == params.Get("configXML") {
+		writeXML(w, 0, failed("configXMLError", "You did not pass a config XML"))
+		return
+	}
+	token := s.nextId("token")
+	s.configs[token] = params.Get("configXML")
+	writeXML(w, 0, &configTokenResponse{status: success(), ConfigToken: token})
+}
+
+func handleHooksCreate(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	callbackURL, meetingID := params.Get("callbackURL"), params.Get("meetingID")
+	if "" == callbackURL {
+		writeXML(w, 0, failed("missingParamCallbackURL", "You must specify a callbackURL in the parameters."))
+		return
+	}
+	for _, hook := range s.hooks {
+		if callbackURL == hook.CallbackURL && meetingID == hook.MeetingId {
+			r := &hookResponse{status: success(), HookId: hook.Id, PermanentHook: hook.Permanent, RawData: hook.Raw}
+			r.MessageKey, r.Message = "duplicateWarning", "There is already a hook for this callback URL."
+			writeXML(w, 0, r)
+			return
+		}
+	}
+	hook := &bbb.Hook{
+		Id:          s.nextId(""),
+		CallbackURL: callbackURL,
+		MeetingId:   meetingID,
+		Raw:         "true" == params.Get("getRaw"),
+	}
+	s.hooks[hook.Id] = hook
+	writeXML(w, 0, &hookResponse{
+		status:        success(),
+		HookId:        hook.Id,
+		PermanentHook: hook.Permanent,
+		RawData:       hook.Raw,
+	})
+}
+
+func handleHooksList(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	meetingID := params.Get("meetingID")
+	r := &hooksResponse{status: success()}
+	for _, hook := range s.hooks {
+		if "" == meetingID || "" == hook.MeetingId || meetingID == hook.MeetingId {
+			r.Hooks = append(r.Hooks, hookXML{
+				HookId:        hook.Id,
+				CallbackURL:   cdata{hook.CallbackURL},
+				MeetingId:     cdata{hook.MeetingId},
+				PermanentHook: hook.Permanent,
+				RawData:       hook.Raw,
+			})
+		}
+	}
+	sort.Slice(r.Hooks, func(i, j int) bool {
+		a, _ := strconv.Atoi(r.Hooks[i].HookId)
+		b, _ := strconv.Atoi(r.Hooks[j].HookId)
+		return a < b
+	})
+	writeXML(w, 0, r)
+}
+
+func handleHooksDestroy(s *Server, w http.ResponseWriter, req *http.Request, params url.Values) {
+	id := params.Get("hookID")
+	if "" == id {
+		writeXML(w, 0, failed("missingParamHookID", "You must specify a hookID in the parameters."))
+		return
+	}
+	if _, t := s.hooks[id]; !t {
+		writeXML(w, 0, failed("destroyMissingHook", "The hook informed was not found."))
+		return
+	}
+	delete(s.hooks, id)
+	writeXML(w, 0, &destroyResponse{status: success(), Removed: true})
+}
+
+func (s *Server) join(m *meeting, a bbb.Attendee) bbb.Attendee {
+	if "" == a.InternalUserId {
+		a.InternalUserId = s.nextId("w_")
+	}
+	if "" == a.UserId {
+		a.UserId = a.InternalUserId
+	}
+	if 0 == len(m.Attendees) {
+		a.IsPresenter = true
+	}
+	if 0 == len(m.Attendees) && m.StartTime.IsZero() {
+		m.StartTime = time.Now()
+	}
+	m.Attendees = append(m.Attendees, a)
+	return a
+}
+
+func (s *Server) end(m *meeting) {
+	for _, id := range m.BreakoutRooms {
+		if room := s.meetingByInternalId(id); nil != room {
+			s.end(room)
+		}
+	}
+	delete(s.meetings, m.Id)
+}
+
+func (s *Server) meetingByInternalId(id string) *meeting {
+	for _, m := range s.meetings {
+		if id == m.InternalId {
+			return m
+		}
+	}
+	return nil
+}
+
+func (s *Server) sortedMeetings() []*meeting {
+	list := make([]*meeting, 0, len(s.meetings))
+	for _, m := range s.meetings {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreateTime.Before(list[j].CreateTime) ||
+			(list[i].CreateTime.Equal(list[j].CreateTime) && list[i].Id < list[j].Id)
+	})
+	return list
+}
+
+func (s *Server) snapshot(m *meeting) bbb.Meeting {
+	meeting := m.Meeting
+	meeting.Running = len(m.Attendees) > 0
+	meeting.NumUsers = len(m.Attendees)
+	meeting.NumMod = 0
+	for _, a := range m.Attendees {
+		if "MODERATOR" == a.Role {
+			meeting.NumMod++
+		}
+	}
+	meeting.Attendees = append([]bbb.Attendee{}, m.Attendees...)
+	meeting.BreakoutRooms = append([]string{}, m.BreakoutRooms...)
+	meeting.Metadata = map[string]string{}
+	for k, v := range m.Metadata {
+		meeting.Metadata[k] = v
+	}
+	return meeting
+}
+
+// recordingIds validates the recordID parameter; every ID must exist.
+func (s *Server) recordingIds(w http.ResponseWriter, params url.Values) ([]string, bool) {
+	ids := splitParam(params.Get("recordID"))
+	if len(ids) < 1 {
+		writeXML(w, 0, failed("missingParamRecordID", "You must specify a recordID."))
+		return nil, false
+	}
+	for _, id := range ids {
+		if _, t := s.recordings[id]; !t {
+			writeXML(w, 0, failed("notFound", "We could not find recordings"))
+			return nil, false
+		}
+	}
+	return ids, true
+}
+
+func splitParam(v string) (list []string) {
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); "" != s {
+			list = append(list, s)
+		}
+	}
+	return
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,335 @@
+// Package bbbtest provides an in-memory BigBlueButton server for testing
+// code that uses the bbb package without a real server.
+//
+//	s := bbbtest.NewServer("secret")
+//	defer s.Close()
+//	b3 := s.NewClient()
+//	m, err := b3.Create("123", &bbb.CreateOptions{Name: "Test"})
+//
+// The server keeps meetings, attendees, recordings, hooks and text tracks in
+// memory, verifies checksums like bbb-web does and answers with the same XML
+// (or JSON) a real server would. Failures and latency can be injected per
+// action with Fail and Delay.
+package bbbtest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sdgoij/gobbb"
+)
+
+const APIPath = "/bigbluebutton/api/"
+
+// Server is a fake BigBlueButton server. Secret, Version and Checksums are
+// read under the server's lock, but should be set before the first request
+// or between requests, never while one is in flight.
+type Server struct {
+	*httptest.Server
+
+	Secret  string
+	Version string
+
+	// Checksums lists the accepted checksum algorithms; all if empty.
+	Checksums []bbb.ChecksumAlgorithm
+
+	m          sync.Mutex
+	meetings   map[string]*meeting
+	recordings map[string]*bbb.Recording
+	tracks     map[string][]bbb.TextTrack
+	hooks      map[string]*bbb.Hook
+	configs    map[string]string
+	failures   map[string]*Failure
+	delays     map[string]time.Duration
+	calls      []string
+	sequence   int
+}
+
+// Failure describes an injected error response.
+type Failure struct {
+	MessageKey string
+	Message    string
+
+	// StatusCode, if not 0, is used as HTTP status. If MessageKey is
+	// empty as well, a plain text body is sent instead of XML.
+	StatusCode int
+
+	// Times limits the number of failing calls; 0 means forever.
+	Times int
+}
+
+type meeting struct {
+	bbb.Meeting
+	documents []Document
+	sessions  map[string]string
+}
+
+// NewServer starts a server using secret as shared secret. The caller
+// should call Close when finished, to shut it down.
+func NewServer(secret string) *Server {
+	s := &Server{Secret: secret, Version: "2.0"}
+	s.Reset()
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// APIURL returns the base URL of the API, suitable for bbb.New.
+func (s *Server) APIURL() string {
+	return s.URL + APIPath
+}
+
+// NewClient returns a client for the server using its shared secret.
+func (s *Server) NewClient() *bbb.BigBlueButton {
+	b3, _ := bbb.New(s.APIURL(), s.Secret)
+	b3.Client = s.Client()
+	return &b3
+}
+
+// Reset removes all state, injected failures and delays.
+func (s *Server) Reset() {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.meetings = map[string]*meeting{}
+	s.recordings = map[string]*bbb.Recording{}
+	s.tracks = map[string][]bbb.TextTrack{}
+	s.hooks = map[string]*bbb.Hook{}
+	s.configs = map[string]string{}
+	s.failures = map[string]*Failure{}
+	s.delays = map[string]time.Duration{}
+	s.calls = nil
+}
+
+// Fail makes calls to action fail as described by f; an empty action fails
+// every call. A nil f removes the failure.
+func (s *Server) Fail(action string, f *Failure) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if nil == f {
+		delete(s.failures, action)
+	} else {
+		failure := *f
+		s.failures[action] = &failure
+	}
+}
+
+// Delay delays every response to action, or to all actions if action is
+// empty, by d.
+func (s *Server) Delay(action string, d time.Duration) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.delays[action] = d
+}
+
+// Calls returns the actions called so far, in order.
+func (s *Server) Calls() []string {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return append([]string{}, s.calls...)
+}
+
+// Meeting returns a copy of the current state of a meeting.
+func (s *Server) Meeting(id string) (bbb.Meeting, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if m, t := s.meetings[id]; t {
+		return s.snapshot(m), true
+	}
+	return bbb.Meeting{}, false
+}
+
+// Documents returns the presentations sent to the meeting with create and
+// insertDocument, inline documents decoded.
+func (s *Server) Documents(meetingID string) []Document {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if m, t := s.meetings[meetingID]; t {
+		return append([]Document(nil), m.documents...)
+	}
+	return nil
+}
+
+// AddAttendee lets a in the meeting as if it had followed a join URL.
+func (s *Server) AddAttendee(meetingID string, a bbb.Attendee) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	m, t := s.meetings[meetingID]
+	if !t {
+		return fmt.Errorf("bbbtest: meeting %q not found", meetingID)
+	}
+	s.join(m, a)
+	return nil
+}
+
+// UpdateAttendee replaces the attendee with the same user ID, e.g. to
+// change its role or presenter state.
+func (s *Server) UpdateAttendee(meetingID string, a bbb.Attendee) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	m, t := s.meetings[meetingID]
+	if !t {
+		return fmt.Errorf("bbbtest: meeting %q not found", meetingID)
+	}
+	for k := range m.Attendees {
+		if a.UserId == m.Attendees[k].UserId {
+			m.Attendees[k] = a
+			return nil
+		}
+	}
+	return fmt.Errorf("bbbtest: attendee %q not found", a.UserId)
+}
+
+// RemoveAttendee removes the attendee with the given user ID.
+func (s *Server) RemoveAttendee(meetingID, userID string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	m, t := s.meetings[meetingID]
+	if !t {
+		return fmt.Errorf("bbbtest: meeting %q not found", meetingID)
+	}
+	for k, a := range m.Attendees {
+		if userID == a.UserId {
+			m.Attendees = append(m.Attendees[:k], m.Attendees[k+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("bbbtest: attendee %q not found", userID)
+}
+
+// SetRecording starts or stops recording the meeting, as if a moderator had
+// pressed the record button.
+func (s *Server) SetRecording(meetingID string, recording bool) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	m, t := s.meetings[meetingID]
+	if !t {
+		return fmt.Errorf("bbbtest: meeting %q not found", meetingID)
+	}
+	m.Recording = recording
+	return nil
+}
+
+// AddRecording stores a copy of r as if it had been processed.
+func (s *Server) AddRecording(r bbb.Recording) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if "" == r.State {
+		r.State = "unpublished"
+		if r.Published {
+			r.State = "published"
+		}
+	}
+	if nil == r.Metadata {
+		r.Metadata = map[string]string{}
+	}
+	s.recordings[r.RecordId] = &r
+}
+
+// Recording returns a copy of a stored recording.
+func (s *Server) Recording(id string) (bbb.Recording, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if r, t := s.recordings[id]; t {
+		return *r, true
+	}
+	return bbb.Recording{}, false
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !strings.HasPrefix(req.URL.Path, APIPath) {
+		http.NotFound(w, req)
+		return
+	}
+	action := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, APIPath), ".xml")
+	s.m.Lock()
+	s.calls = append(s.calls, action)
+	delay := s.delays[""] + s.delays[action]
+	f := s.failure(action)
+	secret, version, checksums := s.Secret, s.Version, s.Checksums
+	s.m.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return
+		}
+	}
+	if nil != f {
+		if "" == f.MessageKey && 0 != f.StatusCode {
+			http.Error(w, http.StatusText(f.StatusCode), f.StatusCode)
+			return
+		}
+		writeXML(w, f.StatusCode, failed(f.MessageKey, f.Message))
+		return
+	}
+	if "" == action {
+		writeXML(w, 0, &versionResponse{status: success(), Version: version})
+		return
+	}
+	h, t := handlers[action]
+	if !t {
+		writeXML(w, 0, failed("unsupportedRequest", "This request is not supported."))
+		return
+	}
+	params, err := verify(action, req, secret, checksums)
+	if nil != err {
+		writeXML(w, 0, err)
+		return
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	h(s, w, req, params)
+}
+
+func (s *Server) failure(action string) *Failure {
+	for _, key := range []string{action, ""} {
+		if f, t := s.failures[key]; t {
+			if f.Times > 0 {
+				if f.Times--; 0 == f.Times {
+					delete(s.failures, key)
+				}
+			}
+			return f
+		}
+	}
+	return nil
+}
+
+// verify checks the checksum the way bbb-web does: over the raw query string
+// without the checksum parameter, or the form values for POST requests.
+func verify(action string, req *http.Request, secret string, checksums []bbb.ChecksumAlgorithm) (url.Values, *statusResponse) {
+	query := req.URL.RawQuery
+	if "setConfigXML" == action && "POST" == req.Method {
+		if err := req.ParseForm(); nil != err {
+			return nil, failed("checksumError", "You did not pass the checksum security check")
+		}
+		query = req.PostForm.Encode()
+	}
+	params, a, err := bbb.VerifyChecksum(action, query, secret)
+	if nil != err || !accepts(checksums, a) {
+		return nil, failed("checksumError", "You did not pass the checksum security check")
+	}
+	return params, nil
+}
+
+func accepts(checksums []bbb.ChecksumAlgorithm, a bbb.ChecksumAlgorithm) bool {
+	if len(checksums) < 1 {
+		return true
+	}
+	for _, accepted := range checksums {
+		if a == accepted {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) nextId(prefix string) string {
+	s.sequence++
+	return fmt.Sprintf("%s%d", prefix, s.sequence)
+}
@@ -0,0 +1,367 @@
+package bbbtest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sdgoij/gobbb"
+)
+
+// Document is a presentation sent to create; Data is decoded from base64.
+type Document struct {
+	Name         string `xml:"name,attr"`
+	Url          string `xml:"url,attr"`
+	Filename     string `xml:"filename,attr"`
+	Downloadable bool   `xml:"downloadable,attr"`
+	Removable    string `xml:"removable,attr"`
+	Current      bool   `xml:"current,attr"`
+	Data         []byte `xml:"-"`
+
+	Value string `xml:",chardata"`
+}
+
+func readDocuments(r io.Reader) ([]Document, error) {
+	var modules struct {
+		Modules []struct {
+			Name      string     `xml:"name,attr"`
+			Documents []Document `xml:"document"`
+		} `xml:"module"`
+	}
+	if err := xml.NewDecoder(r).Decode(&modules); nil != err {
+		return nil, err
+	}
+	var docs []Document
+	for _, module := range modules.Modules {
+		if "presentation" != module.Name {
+			continue
+		}
+		for _, doc := range module.Documents {
+			if v := strings.TrimSpace(doc.Value); "" != v {
+				data, err := base64.StdEncoding.DecodeString(v)
+				if nil != err {
+					return nil, err
+				}
+				doc.Data, doc.Value = data, ""
+			}
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+type status struct {
+	ReturnCode string `xml:"returncode"`
+	MessageKey string `xml:"messageKey,omitempty"`
+	Message    string `xml:"message,omitempty"`
+}
+
+func success() status {
+	return status{ReturnCode: "SUCCESS"}
+}
+
+type statusResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+}
+
+func failed(key, message string) *statusResponse {
+	return &statusResponse{status: status{"FAILED", key, message}}
+}
+
+type versionResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Version string `xml:"version"`
+}
+
+type createXML struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	MeetingId            string `xml:"meetingID"`
+	InternalMeetingId    string `xml:"internalMeetingID"`
+	ParentMeetingId      string `xml:"parentMeetingID"`
+	AttendeePW           string `xml:"attendeePW"`
+	ModeratorPW          string `xml:"moderatorPW"`
+	CreateTime           int64  `xml:"createTime"`
+	VoiceBridge          int    `xml:"voiceBridge"`
+	CreateDate           string `xml:"createDate"`
+	HasUserJoined        bool   `xml:"hasUserJoined"`
+	Duration             int    `xml:"duration"`
+	HasBeenForciblyEnded bool   `xml:"hasBeenForciblyEnded"`
+}
+
+func createResponse(m *meeting) *createXML {
+	parent := m.ParentMeetingID
+	if "" == parent {
+		parent = "bbb-none"
+	}
+	return &createXML{
+		status:            success(),
+		MeetingId:         m.Id,
+		InternalMeetingId: m.InternalId,
+		ParentMeetingId:   parent,
+		AttendeePW:        m.AttendeePW,
+		ModeratorPW:       m.ModeratorPW,
+		CreateTime:        mstime(m.CreateTime),
+		VoiceBridge:       m.VoiceBridge,
+		CreateDate:        m.CreateTime.Format(time.UnixDate),
+		HasUserJoined:     len(m.Attendees) > 0,
+	}
+}
+
+type joinResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	MeetingId    string `xml:"meeting_id"`
+	UserId       string `xml:"user_id"`
+	AuthToken    string `xml:"auth_token"`
+	SessionToken string `xml:"session_token"`
+	Url          string `xml:"url"`
+}
+
+type runningResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Running bool `xml:"running"`
+}
+
+type attendeeXML struct {
+	UserId          string      `xml:"userID"`
+	FullName        string      `xml:"fullName"`
+	Role            string      `xml:"role"`
+	IsPresenter     bool        `xml:"isPresenter"`
+	IsListeningOnly bool        `xml:"isListeningOnly"`
+	HasJoinedVoice  bool        `xml:"hasJoinedVoice"`
+	HasVideo        bool        `xml:"hasVideo"`
+	ClientType      string      `xml:"clientType"`
+	CustomData      metadataXML `xml:"customdata,omitempty"`
+}
+
+type breakoutXML struct {
+	ParentMeetingId string `xml:"parentMeetingID"`
+	Sequence        int    `xml:"sequence"`
+	FreeJoin        bool   `xml:"freeJoin"`
+}
+
+type meetingXML struct {
+	MeetingName           string        `xml:"meetingName"`
+	MeetingId             string        `xml:"meetingID"`
+	InternalMeetingId     string        `xml:"internalMeetingID"`
+	CreateTime            int64         `xml:"createTime"`
+	CreateDate            string        `xml:"createDate"`
+	VoiceBridge           int           `xml:"voiceBridge"`
+	AttendeePW            string        `xml:"attendeePW"`
+	ModeratorPW           string        `xml:"moderatorPW"`
+	Running               bool          `xml:"running"`
+	Duration              int           `xml:"duration"`
+	HasUserJoined         bool          `xml:"hasUserJoined"`
+	Recording             bool          `xml:"recording"`
+	HasBeenForciblyEnded  bool          `xml:"hasBeenForciblyEnded"`
+	StartTime             int64         `xml:"startTime"`
+	EndTime               int64         `xml:"endTime"`
+	ParticipantCount      int           `xml:"participantCount"`
+	ListenerCount         int           `xml:"listenerCount"`
+	VoiceParticipantCount int           `xml:"voiceParticipantCount"`
+	VideoCount            int           `xml:"videoCount"`
+	MaxUsers              int           `xml:"maxUsers"`
+	ModeratorCount        int           `xml:"moderatorCount"`
+	Attendees             []attendeeXML `xml:"attendees>attendee"`
+	Metadata              metadataXML   `xml:"metadata"`
+	IsBreakout            bool          `xml:"isBreakout"`
+	Breakout              *breakoutXML  `xml:"breakout,omitempty"`
+	BreakoutRooms         []string      `xml:"breakoutRooms>breakout,omitempty"`
+}
+
+func (s *Server) meetingXML(m *meeting) meetingXML {
+	snapshot := s.snapshot(m)
+	x := meetingXML{
+		MeetingName:       snapshot.Name,
+		MeetingId:         snapshot.Id,
+		InternalMeetingId: snapshot.InternalId,
+		CreateTime:        mstime(snapshot.CreateTime),
+		CreateDate:        snapshot.CreateTime.Format(time.UnixDate),
+		VoiceBridge:       snapshot.VoiceBridge,
+		AttendeePW:        snapshot.AttendeePW,
+		ModeratorPW:       snapshot.ModeratorPW,
+		Running:           snapshot.Running,
+		HasUserJoined:     !snapshot.StartTime.IsZero(),
+		Recording:         snapshot.Recording,
+		StartTime:         mstime(snapshot.StartTime),
+		EndTime:           mstime(snapshot.EndTime),
+		ParticipantCount:  snapshot.NumUsers,
+		MaxUsers:          snapshot.MaxUsers,
+		ModeratorCount:    snapshot.NumMod,
+		Metadata:          metadataXML(snapshot.Metadata),
+		IsBreakout:        snapshot.IsBreakout,
+		BreakoutRooms:     snapshot.BreakoutRooms,
+	}
+	for _, a := range snapshot.Attendees {
+		x.Attendees = append(x.Attendees, attendeeXML{
+			UserId:          a.UserId,
+			FullName:        a.Name,
+			Role:            a.Role,
+			IsPresenter:     a.IsPresenter,
+			IsListeningOnly: a.IsListeningOnly,
+			HasJoinedVoice:  a.HasJoinedVoice,
+			HasVideo:        a.HasVideo,
+			ClientType:      a.ClientType,
+			CustomData:      metadataXML(a.CustomData),
+		})
+		if a.HasJoinedVoice {
+			x.VoiceParticipantCount++
+		}
+		if a.IsListeningOnly {
+			x.ListenerCount++
+		}
+		if a.HasVideo {
+			x.VideoCount++
+		}
+	}
+	if snapshot.IsBreakout {
+		x.Breakout = &breakoutXML{
+			ParentMeetingId: snapshot.ParentMeetingID,
+			Sequence:        snapshot.Sequence,
+			FreeJoin:        snapshot.FreeJoin,
+		}
+	}
+	return x
+}
+
+type meetingInfoResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	meetingXML
+}
+
+type meetingsResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Meetings []meetingXML `xml:"meetings>meeting"`
+}
+
+type recordingsResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Recordings []*bbb.Recording `xml:"recordings>recording"`
+	Total      *int             `xml:"totalElements,omitempty"`
+}
+
+type publishResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Published bool `xml:"published"`
+}
+
+type deleteResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Deleted bool `xml:"deleted"`
+}
+
+type updateResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Updated bool `xml:"updated"`
+}
+
+type configTokenResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	ConfigToken string `xml:"configToken"`
+}
+
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+type hookXML struct {
+	HookId        string `xml:"hookID"`
+	CallbackURL   cdata  `xml:"callbackURL"`
+	MeetingId     cdata  `xml:"meetingID"`
+	PermanentHook bool   `xml:"permanentHook"`
+	RawData       bool   `xml:"rawData"`
+}
+
+type hookResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	HookId        string `xml:"hookID"`
+	PermanentHook bool   `xml:"permanentHook"`
+	RawData       bool   `xml:"rawData"`
+}
+
+type hooksResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Hooks []hookXML `xml:"hooks>hook"`
+}
+
+type destroyResponse struct {
+	XMLName xml.Name `xml:"response"`
+	status
+	Removed bool `xml:"removed"`
+}
+
+// metadataXML encodes a map as one element per key, sorted by key.
+type metadataXML map[string]string
+
+func (m metadataXML) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); nil != err {
+		return err
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := e.EncodeElement(m[k], xml.StartElement{Name: xml.Name{Local: k}}); nil != err {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+type jsonResponse struct {
+	ReturnCode string          `json:"returncode"`
+	MessageKey string          `json:"messageKey,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	RecordId   string          `json:"recordId,omitempty"`
+	Tracks     []bbb.TextTrack `json:"tracks,omitempty"`
+}
+
+func jsonFailed(key, message string) *jsonResponse {
+	return &jsonResponse{ReturnCode: "FAILED", MessageKey: key, Message: message}
+}
+
+func writeXML(w http.ResponseWriter, code int, v interface{}) {
+	data, err := xml.Marshal(v)
+	if nil != err {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if 0 == code {
+		code = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "text/xml;charset=UTF-8")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func writeJSON(w http.ResponseWriter, r *jsonResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]*jsonResponse{"response": r})
+}
+
+func mstime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,144 @@
+package bbb
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// ChecksumAlgorithm selects the hash used to sign API calls. The zero value
+// is SHA1, which every BigBlueButton server accepts unless configured
+// otherwise (see supportedChecksumAlgorithms in bbb-web.properties).
+type ChecksumAlgorithm int
+
+const (
+	SHA1 ChecksumAlgorithm = iota
+	SHA256
+	SHA384
+	SHA512
+)
+
+func (a ChecksumAlgorithm) String() string {
+	switch a {
+	case SHA1:
+		return "sha1"
+	case SHA256:
+		return "sha256"
+	case SHA384:
+		return "sha384"
+	case SHA512:
+		return "sha512"
+	}
+	return fmt.Sprintf("ChecksumAlgorithm(%d)", int(a))
+}
+
+func (a ChecksumAlgorithm) hash() hash.Hash {
+	switch a {
+	case SHA256:
+		return sha256.New()
+	case SHA384:
+		return sha512.New384()
+	case SHA512:
+		return sha512.New()
+	}
+	return sha1.New()
+}
+
+// ChecksumAlgorithmOf returns the algorithm producing hex checksums as long
+// as checksum.
+func ChecksumAlgorithmOf(checksum string) (ChecksumAlgorithm, bool) {
+	switch len(checksum) {
+	case 40:
+		return SHA1, true
+	case 64:
+		return SHA256, true
+	case 96:
+		return SHA384, true
+	case 128:
+		return SHA512, true
+	}
+	return SHA1, false
+}
+
+// Sum returns the hex checksum of the concatenated strings.
+func (a ChecksumAlgorithm) Sum(s ...string) string {
+	h := a.hash()
+	for _, s := range s {
+		io.WriteString(h, s)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// VerifyChecksum checks an incoming API call the way bbb-web does, the
+// inverse of the URLs BigBlueButton builds. query is the raw query string
+// including the checksum, which is computed over the query without it. The
+// algorithm is chosen by the length of the checksum; any of the secrets may
+// have been used. It returns the parameters without the checksum, or a
+// checksumError.
+func VerifyChecksum(action, query string, secrets ...string) (url.Values, ChecksumAlgorithm, error) {
+	fail := &APIError{
+		Action:     action,
+		ReturnCode: "FAILED",
+		MessageKey: "checksumError",
+		Message:    "You did not pass the checksum security check",
+	}
+	params, err := url.ParseQuery(query)
+	if nil != err {
+		return nil, SHA1, fail
+	}
+	checksum := strings.ToLower(params.Get("checksum"))
+	a, ok := ChecksumAlgorithmOf(checksum)
+	if !ok {
+		return nil, SHA1, fail
+	}
+	parts := []string{}
+	for _, part := range strings.Split(query, "&") {
+		if "checksum" != part && !strings.HasPrefix(part, "checksum=") {
+			parts = append(parts, part)
+		}
+	}
+	query = strings.Join(parts, "&")
+	for _, secret := range secrets {
+		if 1 == subtle.ConstantTimeCompare([]byte(a.Sum(action, query, secret)), []byte(checksum)) {
+			params.Del("checksum")
+			return params, a, nil
+		}
+	}
+	return nil, a, fail
+}
+
+// DetectChecksumAlgorithm probes the server with getMeetings, from the
+// strongest algorithm to the weakest, and configures the first one that is
+// not rejected with a checksumError.
+func (b3 *BigBlueButton) DetectChecksumAlgorithm() (ChecksumAlgorithm, error) {
+	return b3.DetectChecksumAlgorithmWithContext(context.Background())
+}
+
+func (b3 *BigBlueButton) DetectChecksumAlgorithmWithContext(ctx context.Context) (ChecksumAlgorithm, error) {
+	var err error
+	for _, a := range []ChecksumAlgorithm{SHA512, SHA384, SHA256, SHA1} {
+		probe := *b3
+		probe.Checksum = a
+		if _, err = probe.MeetingsWithContext(ctx); nil == err {
+			b3.Checksum = a
+			return a, nil
+		} else if !IsChecksumError(err) {
+			return b3.Checksum, err
+		}
+	}
+	return b3.Checksum, err
+}
+
+func (b3 *BigBlueButton) checksum(action, params string) string {
+	if i := len(params) - 1; i > 0 && params[i] == '&' {
+		params = params[:i]
+	}
+	return b3.Checksum.Sum(action, params, b3.Secret)
+}
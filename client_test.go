@@ -0,0 +1,938 @@
+package bbb_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sdgoij/gobbb"
+	"github.com/sdgoij/gobbb/bbbtest"
+)
+
+func TestCreate(t *testing.T) {
+	s := bbbtest.NewServer("secret")
+	defer s.Close()
+	b3 := s.NewClient()
+
+	m, err := b3.Create("123", &bbb.CreateOptions{
+		Name:        "Test",
+		AttendeePW:  "ap",
+		ModeratorPW: "mp",
+		Metadata:    map[string]string{"course": "cs101"},
+	})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "123" != m.Id || "ap" != m.AttendeePW || "mp" != m.ModeratorPW || m.CreateTime.IsZero() {
+		t.Errorf("unexpected meeting: %#v", m)
+	}
+	if _, err := b3.Create("123", &bbb.CreateOptions{ModeratorPW: "other"}); !bbb.IsDuplicate(err) {
+		t.Errorf("expected idNotUnique, got %v", err)
+	}
+	if m, err := b3.Create("123", bbb.EmptyOptions); nil != err || "mp" != m.ModeratorPW {
+		t.Errorf("expected duplicate meeting, got %v (%v)", m, err)
+	}
+
+	info, err := b3.MeetingInfo("123", "mp")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "Test" != info.Name || info.Running || "cs101" != info.Metadata["course"] {
+		t.Errorf("unexpected meeting info: %#v", info)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	s := bbbtest.NewServer("secret")
+	defer s.Close()
+	b3 := s.NewClient()
+
+	if _, err := b3.Create("123", &bbb.CreateOptions{AttendeePW: "ap", ModeratorPW: "mp"}); nil != err {
+		t.Fatal(err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	for _, join := range []struct{ name, password string }{{"Tim", "mp"}, {"Tom", "ap"}} {
+		res, err := client.Get(b3.JoinURL(join.name, "123", join.password, &bbb.JoinOptions{UserId: join.name}))
+		if nil != err {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if http.StatusFound != res.StatusCode {
+			t.Fatalf("expected redirect, got %s", res.Status)
+		}
+	}
+	if running, err := b3.IsMeetingRunning("123"); nil != err || !running {
+		t.Errorf("expected running meeting, got %v (%v)", running, err)
+	}
+	m, err := b3.MeetingInfo("123", "mp")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 2 != m.NumUsers || 1 != m.NumMod || 2 != len(m.Attendees) ||
+		"MODERATOR" != m.Attendees[0].Role || "Tom" != m.Attendees[1].UserId {
+		t.Errorf("unexpected meeting info: %#v", m)
+	}
+
+	if err := b3.End("123", "mp", &bbb.EndOptions{Wait: bbb.EndWaitGone}); nil != err {
+		t.Errorf("expected meeting to end, got %v", err)
+	}
+	if err := b3.End("123", "mp", nil); !bbb.IsNotFound(err) {
+		t.Errorf("expected notFound, got %v", err)
+	}
+	if _, err := b3.MeetingInfo("123", "mp"); !bbb.IsNotFound(err) {
+		t.Errorf("expected notFound, got %v", err)
+	}
+	if meetings, err := b3.Meetings(); nil != err || 0 != len(meetings) {
+		t.Errorf("expected no meetings, got %v (%v)", meetings, err)
+	}
+}
+
+func TestRecordingsAPI(t *testing.T) {
+	s := bbbtest.NewServer("secret")
+	defer s.Close()
+	b3 := s.NewClient()
+
+	s.AddRecording(bbb.Recording{
+		RecordId:  "r1",
+		MeetingId: "123",
+		Name:      "Lecture",
+		StartTime: time.Unix(1530718721, 0),
+		Playback:  []bbb.Playback{{Type: "presentation", Url: "http://example.com/p", Length: time.Minute}},
+	})
+	s.AddRecording(bbb.Recording{RecordId: "r2", MeetingId: "456"})
+
+	recordings, err := b3.Recordings([]string{"123"})
+	if nil != err || 1 != len(recordings) {
+		t.Fatalf("unexpected recordings: %v (%v)", recordings, err)
+	}
+	if r := recordings[0]; r.Published || "unpublished" != r.State || time.Minute != r.Playback[0].Length {
+		t.Errorf("unexpected recording: %#v", r)
+	}
+	if published, err := b3.PublishRecordings([]string{"r1"}, true); nil != err || !published {
+		t.Errorf("expected published, got %v (%v)", published, err)
+	}
+	if _, err := b3.PublishRecordings([]string{"nope"}, true); !bbb.IsNotFound(err) {
+		t.Errorf("expected notFound, got %v", err)
+	}
+	if _, err := b3.UpdateRecordings([]string{"r1"}, map[string]string{"name": "Renamed"}); nil != err {
+		t.Error(err)
+	}
+	if r, _ := s.Recording("r1"); !r.Published || "Renamed" != r.Metadata["name"] {
+		t.Errorf("unexpected recording state: %#v", r)
+	}
+
+	options := &bbb.TextTrackOptions{Lang: "en-US", Label: "English"}
+	if err := b3.PutRecordingTextTrack("r1", options, strings.NewReader("WEBVTT\n")); nil != err {
+		t.Fatal(err)
+	}
+	if tracks, err := b3.RecordingTextTracks("r1"); nil != err || 1 != len(tracks) || "English" != tracks[0].Label {
+		t.Errorf("unexpected tracks: %v (%v)", tracks, err)
+	}
+
+	if deleted, err := b3.DeleteRecordings([]string{"r1", "r2"}); nil != err || !deleted {
+		t.Errorf("expected deleted, got %v (%v)", deleted, err)
+	}
+	if recordings, err := b3.Recordings(nil); nil != err || 0 != len(recordings) {
+		t.Errorf("expected no recordings, got %v (%v)", recordings, err)
+	}
+}
+
+func TestHooksAPI(t *testing.T) {
+	s := bbbtest.NewServer("secret")
+	defer s.Close()
+	hooks := s.NewClient().Hooks()
+
+	global, err := hooks.Create("http://example.com/all", &bbb.HookOptions{})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if _, err := hooks.Create("http://example.com/123", &bbb.HookOptions{MeetingId: "123"}); nil != err {
+		t.Fatal(err)
+	}
+	if hook, err := hooks.Create("http://example.com/all", &bbb.HookOptions{}); nil != err || global.Id != hook.Id {
+		t.Errorf("expected existing hook %s, got %v (%v)", global.Id, hook, err)
+	}
+	if list, err := hooks.List("456"); nil != err || 1 != len(list) || global.Id != list[0].Id {
+		t.Errorf("unexpected hooks: %v (%v)", list, err)
+	}
+	if removed, err := hooks.Destroy(global.Id); nil != err || !removed {
+		t.Errorf("expected removed hook, got %v (%v)", removed, err)
+	}
+	if _, err := hooks.Destroy(global.Id); !bbb.IsNotFound(err) {
+		t.Errorf("expected destroyMissingHook, got %v", err)
+	}
+}
+
+func TestConfigXML(t *testing.T) {
+	s := bbbtest.NewServer("secret")
+	defer s.Close()
+	b3 := s.NewClient()
+
+	config, err := b3.DefaultConfigXML()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if _, err := b3.SetConfigXML("123", config); !bbb.IsNotFound(err) {
+		t.Errorf("expected notFound, got %v", err)
+	}
+	b3.Create("123", bbb.EmptyOptions)
+	if token, err := b3.SetConfigXML("123", config); nil != err || "" == token {
+		t.Errorf("expected config token, got %q (%v)", token, err)
+	}
+}
+
+func TestServerChecksum(t *testing.T) {
+	s := bbbtest.NewServer("secret")
+	defer s.Close()
+	s.Checksums = []bbb.ChecksumAlgorithm{bbb.SHA256, bbb.SHA384}
+
+	b3 := s.NewClient()
+	if _, err := b3.Meetings(); !bbb.IsChecksumError(err) {
+		t.Errorf("expected checksumError for sha1, got %v", err)
+	}
+	if a, err := b3.DetectChecksumAlgorithm(); nil != err || bbb.SHA384 != a {
+		t.Errorf("expected sha384, got %s (%v)", a, err)
+	}
+	b3.Secret = "wrong"
+	if _, err := b3.Meetings(); !bbb.IsChecksumError(err) {
+		t.Errorf("expected checksumError for wrong secret, got %v", err)
+	}
+}
+
+func TestServerFailures(t *testing.T) {
+	s := bbbtest.NewServer("secret")
+	defer s.Close()
+	b3 := s.NewClient()
+
+	s.Fail("getMeetings", &bbbtest.Failure{MessageKey: "internalError", Message: "boom", Times: 1})
+	if _, err := b3.Meetings(); nil == err || err.(*bbb.APIError).MessageKey != "internalError" {
+		t.Errorf("expected internalError, got %v", err)
+	}
+	if _, err := b3.Meetings(); nil != err {
+		t.Errorf("expected failure to be used up, got %v", err)
+	}
+
+	s.Fail("", &bbbtest.Failure{StatusCode: http.StatusBadGateway})
+	if _, err := b3.ServerVersion(); nil == err || err.(*bbb.APIError).StatusCode != http.StatusBadGateway {
+		t.Errorf("expected HTTP 502, got %v", err)
+	}
+	s.Fail("", nil)
+
+	s.Delay("getMeetings", time.Second)
+	b3.Client = &http.Client{Timeout: 50 * time.Millisecond}
+	if _, err := b3.Meetings(); nil == err {
+		t.Error("expected timeout")
+	}
+	if calls := s.Calls(); 4 != len(calls) || "getMeetings" != calls[3] {
+		t.Errorf("unexpected calls: %v", calls)
+	}
+}
+
+func TestJoinOptions(t *testing.T) {
+	s := bbbtest.NewServer("secret")
+	defer s.Close()
+	b3 := s.NewClient()
+	b3.Create("123", bbb.EmptyOptions)
+
+	joinURL := b3.JoinURL("Tim", "123", "", &bbb.JoinOptions{
+		UserId:   "u1",
+		Role:     bbb.RoleModerator,
+		Redirect: bbb.Bool(false),
+		UserData: map[string]string{"bbb_auto_join_audio": "false"},
+	})
+	if !strings.Contains(joinURL, "redirect=false") || strings.Contains(joinURL, "password=") {
+		t.Errorf("unexpected join URL: %s", joinURL)
+	}
+	res, err := http.Get(joinURL)
+	if nil != err {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	m, err := b3.MeetingInfo("123", "")
+	if nil != err || 1 != len(m.Attendees) {
+		t.Fatalf("unexpected meeting: %v (%v)", m, err)
+	}
+	if a := m.Attendees[0]; bbb.RoleModerator != a.Role || "false" != a.CustomData["bbb_auto_join_audio"] {
+		t.Errorf("unexpected attendee: %#v", a)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	res, err = client.Get(b3.JoinURL("Tom", "123", "wrong", &bbb.JoinOptions{
+		ErrorRedirectUrl: "http://example.com/error",
+	}))
+	if nil != err {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if u := res.Header.Get("Location"); !strings.HasPrefix(u, "http://example.com/error?errors=") {
+		t.Errorf("expected error redirect, got %q", u)
+	}
+}
+
+func TestCreateDocuments(t *testing.T) {
+	s := bbbtest.NewServer("secret")
+	defer s.Close()
+	b3 := s.NewClient()
+
+	dir, err := ioutil.TempDir("", "gobbb")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "slides.pdf")
+	if err := ioutil.WriteFile(path, []byte("%PDF-1.4 slides"), 0644); nil != err {
+		t.Fatal(err)
+	}
+	file, err := bbb.NewDocumentFile(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	file.Downloadable = true
+	url := bbb.NewDocumentURL("https://example.com/default.pdf", "default.pdf")
+	url.Current = true
+
+	if _, err := b3.Create("docs", &bbb.CreateOptions{Documents: []bbb.ConfigXML_Document{
+		url, file, bbb.NewDocumentReader("notes", "application/pdf", strings.NewReader("<notes & more>")),
+	}}); nil != err {
+		t.Fatal(err)
+	}
+	docs := s.Documents("docs")
+	if 3 != len(docs) {
+		t.Fatalf("expected 3 documents, got %#v", docs)
+	}
+	if "https://example.com/default.pdf" != docs[0].Url || "default.pdf" != docs[0].Filename || !docs[0].Current {
+		t.Errorf("unexpected url document: %#v", docs[0])
+	}
+	if "slides.pdf" != docs[1].Name || "%PDF-1.4 slides" != string(docs[1].Data) || !docs[1].Downloadable {
+		t.Errorf("unexpected file document: %#v", docs[1])
+	}
+	if "notes.pdf" != docs[2].Name || "<notes & more>" != string(docs[2].Data) {
+		t.Errorf("unexpected reader document: %#v", docs[2])
+	}
+
+	b3.MaxDocumentSize = 4
+	options := &bbb.CreateOptions{Documents: []bbb.ConfigXML_Document{file}}
+	if _, err := b3.Create("large", options); !errors.Is(err, bbb.ErrDocumentTooLarge) {
+		t.Errorf("expected ErrDocumentTooLarge, got %v", err)
+	}
+	options.Documents[0] = bbb.NewDocumentReader("large.pdf", "", strings.NewReader("too large"))
+	if _, err := b3.Create("large", options); nil == err {
+		t.Error("expected error for large reader")
+	}
+	if _, found := s.Meeting("large"); found {
+		t.Error("meeting created despite large document")
+	}
+}
+
+func TestInsertDocument(t *testing.T) {
+	s := bbbtest.NewServer("secret")
+	defer s.Close()
+	b3 := s.NewClient()
+
+	doc := bbb.NewDocumentURL("https://example.com/worksheet.pdf", "worksheet.pdf")
+	if err := b3.InsertDocument("123", []bbb.ConfigXML_Document{doc}); !bbb.IsNotFound(err) {
+		t.Errorf("expected notFound, got %v", err)
+	}
+	if _, err := b3.Create("123", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	if err := b3.InsertDocument("123", nil); nil == err {
+		t.Error("expected missing document error")
+	}
+	if err := b3.InsertDocument("123", []bbb.ConfigXML_Document{
+		bbb.NewDocumentReader("worksheet.pdf", "application/pdf", strings.NewReader("worksheet")),
+		bbb.NewDocumentURL("https://example.com/answers.pdf", "answers.pdf"),
+	}); nil != err {
+		t.Fatal(err)
+	}
+	docs := s.Documents("123")
+	if 2 != len(docs) || "worksheet" != string(docs[0].Data) || "answers.pdf" != docs[1].Filename {
+		t.Errorf("unexpected documents: %#v", docs)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	s := bbbtest.NewServer("secret")
+	defer s.Close()
+	b3 := s.NewClient()
+
+	if _, err := b3.Create("idle", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	w := bbb.NewWatcher(b3, 5*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	expect := func(types ...bbb.WatchEventType) []bbb.WatchEvent {
+		t.Helper()
+		events := []bbb.WatchEvent{}
+		for _, typ := range types {
+			select {
+			case ev := <-w.Events():
+				if typ != ev.Type {
+					t.Fatalf("expected %v, got %v (%#v)", typ, ev.Type, ev)
+				}
+				events = append(events, ev)
+			case <-time.After(time.Second):
+				t.Fatalf("timeout waiting for %v", typ)
+			}
+		}
+		return events
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := b3.Create("123", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	s.AddAttendee("123", bbb.Attendee{UserId: "alice", Name: "Alice", Role: bbb.RoleViewer})
+	if ev := expect(bbb.MeetingStarted, bbb.UserJoined); "123" != ev[0].Meeting.Id || "alice" != ev[1].Attendee.UserId {
+		t.Errorf("unexpected events: %#v", ev)
+	}
+	m, _ := s.Meeting("123")
+	alice := m.Attendees[0]
+	alice.Role = bbb.RoleModerator
+	s.UpdateAttendee("123", alice)
+	if ev := expect(bbb.RoleChanged); bbb.RoleViewer != ev[0].Previous.Role || bbb.RoleModerator != ev[0].Attendee.Role {
+		t.Errorf("unexpected role change: %#v", ev[0])
+	}
+	s.AddAttendee("123", bbb.Attendee{UserId: "bob", Name: "Bob", Role: bbb.RoleViewer})
+	expect(bbb.UserJoined)
+	alice.IsPresenter = false
+	s.UpdateAttendee("123", alice)
+	if ev := expect(bbb.PresenterChanged); nil != ev[0].Attendee || "alice" != ev[0].Previous.UserId {
+		t.Errorf("unexpected presenter change: %#v", ev[0])
+	}
+	s.SetRecording("123", true)
+	expect(bbb.RecordingStarted)
+	s.RemoveAttendee("123", "bob")
+	if ev := expect(bbb.UserLeft); "bob" != ev[0].Attendee.UserId {
+		t.Errorf("unexpected user left: %#v", ev[0])
+	}
+
+	w.Watch("123", "")
+	time.Sleep(20 * time.Millisecond)
+	if _, err := b3.Create("other", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	s.AddAttendee("other", bbb.Attendee{UserId: "carol", Name: "Carol"})
+	b3.End("123", "", nil)
+	if ev := expect(bbb.UserLeft, bbb.MeetingEnded); "alice" != ev[0].Attendee.UserId || "123" != ev[1].Meeting.Id {
+		t.Errorf("unexpected events: %#v", ev)
+	}
+
+	cancel()
+	if err := <-done; context.Canceled != err {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	for ev := range w.Events() {
+		t.Errorf("unexpected event: %#v", ev)
+	}
+}
+
+func TestWatcherBaseline(t *testing.T) {
+	s := bbbtest.NewServer("secret")
+	defer s.Close()
+	b3 := s.NewClient()
+	for _, id := range []string{"a", "b"} {
+		if _, err := b3.Create(id, bbb.EmptyOptions); nil != err {
+			t.Fatal(err)
+		}
+		s.AddAttendee(id, bbb.Attendee{UserId: "alice", Name: "Alice"})
+	}
+	s.Fail("getMeetings", &bbbtest.Failure{Times: 1})
+
+	w := bbb.NewWatcher(b3, 5*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+	next := func() bbb.WatchEvent {
+		t.Helper()
+		select {
+		case ev := <-w.Events():
+			return ev
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for event")
+		}
+		return bbb.WatchEvent{}
+	}
+
+	if ev := next(); bbb.WatchFailed != ev.Type {
+		t.Fatalf("expected %v, got %v", bbb.WatchFailed, ev.Type)
+	}
+	time.Sleep(20 * time.Millisecond)
+	s.AddAttendee("a", bbb.Attendee{UserId: "bob", Name: "Bob"})
+	if ev := next(); bbb.UserJoined != ev.Type || "bob" != ev.Attendee.UserId {
+		t.Fatalf("expected bob joining, got %#v", ev)
+	}
+
+	w.Watch("a", "")
+	time.Sleep(20 * time.Millisecond)
+	w.Watch("b", "")
+	time.Sleep(20 * time.Millisecond)
+	s.AddAttendee("b", bbb.Attendee{UserId: "carol", Name: "Carol"})
+	if ev := next(); bbb.UserJoined != ev.Type || "carol" != ev.Attendee.UserId {
+		t.Fatalf("expected carol joining, got %#v", ev)
+	}
+}
+
+func TestEnd(t *testing.T) {
+	s := bbbtest.NewServer("secret")
+	defer s.Close()
+	b3 := s.NewClient()
+
+	for _, wait := range []bbb.EndStrategy{bbb.EndNoWait, bbb.EndWaitGone, bbb.EndWaitNotRunning} {
+		if _, err := b3.Create("123", bbb.EmptyOptions); nil != err {
+			t.Fatal(err)
+		}
+		s.AddAttendee("123", bbb.Attendee{UserId: "alice", Name: "Alice"})
+		if err := b3.End("123", "", &bbb.EndOptions{Wait: wait}); nil != err {
+			t.Errorf("%d: %v", wait, err)
+		}
+		if running, _ := b3.IsMeetingRunning("123"); running {
+			t.Errorf("%d: meeting still running", wait)
+		}
+	}
+
+	if _, err := b3.Create("123", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	s.Fail("getMeetingInfo", &bbbtest.Failure{StatusCode: http.StatusServiceUnavailable})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := b3.EndWithContext(ctx, "123", "", &bbb.EndOptions{
+		Wait:     bbb.EndWaitGone,
+		Interval: 5 * time.Millisecond,
+	})
+	if context.DeadlineExceeded != err {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if _, found := s.Meeting("123"); found {
+		t.Error("meeting not ended")
+	}
+
+	if _, err := b3.Create("123", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	s.Fail("getMeetingInfo", &bbbtest.Failure{MessageKey: "checksumError"})
+	err = b3.End("123", "", &bbb.EndOptions{Wait: bbb.EndWaitGone, Interval: 5 * time.Millisecond})
+	if !bbb.IsChecksumError(err) {
+		t.Errorf("expected checksumError, got %v", err)
+	}
+
+	if _, err := b3.Create("123", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	s.Fail("getMeetingInfo", &bbbtest.Failure{StatusCode: http.StatusServiceUnavailable})
+	err = b3.End("123", "", &bbb.EndOptions{
+		Wait:     bbb.EndWaitGone,
+		Interval: 5 * time.Millisecond,
+		MaxWait:  50 * time.Millisecond,
+	})
+	if context.DeadlineExceeded != err {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestIterateRecordings(t *testing.T) {
+	s := bbbtest.NewServer("secret")
+	defer s.Close()
+	b3 := s.NewClient()
+
+	states := []string{bbb.RecordingPublished, bbb.RecordingUnpublished, bbb.RecordingProcessing}
+	for i := 0; i < 250; i++ {
+		s.AddRecording(bbb.Recording{
+			RecordId:  fmt.Sprintf("r%03d", i),
+			MeetingId: "123",
+			State:     states[i%3],
+			Published: 0 == i%3,
+			StartTime: time.Unix(int64(1530718721+i), 0),
+			Metadata:  map[string]string{"course": fmt.Sprintf("cs%d", i%5)},
+		})
+	}
+
+	page, err := b3.RecordingsPage(&bbb.RecordingsOptions{Offset: 10, Limit: 5})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 167 != page.Total || 10 != page.Offset || 5 != len(page.Recordings) || "r015" != page.Recordings[0].RecordId {
+		t.Errorf("unexpected page: %d %d %v", page.Total, page.Offset, page.Recordings)
+	}
+
+	it := b3.IterateRecordings(context.Background(), &bbb.RecordingsOptions{
+		States:   []string{bbb.RecordingAnyState},
+		Metadata: map[string]string{"course": "cs1"},
+		Limit:    7,
+	})
+	ids := []string{}
+	for it.Next() {
+		ids = append(ids, it.Recording().RecordId)
+	}
+	if nil != it.Err() || 50 != len(ids) || 50 != it.Total() || "r001" != ids[0] || "r246" != ids[49] {
+		t.Errorf("unexpected iteration: %v %d (%v)", ids, it.Total(), it.Err())
+	}
+
+	it = b3.IterateRecordings(context.Background(), &bbb.RecordingsOptions{States: []string{bbb.RecordingProcessing}})
+	n := 0
+	for it.Next() {
+		if bbb.RecordingProcessing != it.Recording().State {
+			t.Errorf("unexpected state: %#v", it.Recording())
+		}
+		n++
+	}
+	if nil != it.Err() || 83 != n {
+		t.Errorf("expected 83 processing recordings, got %d (%v)", n, it.Err())
+	}
+
+	s.Fail("getRecordings", &bbbtest.Failure{MessageKey: "checksumError"})
+	if it := b3.IterateRecordings(context.Background(), nil); it.Next() || !bbb.IsChecksumError(it.Err()) {
+		t.Errorf("expected checksumError, got %v", it.Err())
+	}
+}
+
+type countingAPI struct {
+	bbb.API
+	pages      int
+	recordings int
+}
+
+func (api *countingAPI) RecordingsPageWithContext(ctx context.Context, options *bbb.RecordingsOptions) (*bbb.RecordingPage, error) {
+	api.pages++
+	page, err := api.API.RecordingsPageWithContext(ctx, options)
+	if nil == err {
+		api.recordings += len(page.Recordings)
+	}
+	return page, err
+}
+
+func TestAPIDecorator(t *testing.T) {
+	s := bbbtest.NewServer("secret")
+	defer s.Close()
+	for i := 0; i < 5; i++ {
+		s.AddRecording(bbb.Recording{RecordId: fmt.Sprintf("r%d", i), MeetingId: "123"})
+	}
+
+	api := &countingAPI{API: s.NewClient()}
+	it, n := bbb.NewRecordingIterator(context.Background(), api, &bbb.RecordingsOptions{Limit: 2}), 0
+	for it.Next() {
+		n++
+	}
+	if nil != it.Err() || 5 != n || 3 != api.pages {
+		t.Errorf("expected 5 recordings in 3 pages, got %d in %d (%v)", n, api.pages, it.Err())
+	}
+}
+
+func TestPool(t *testing.T) {
+	servers := make([]*bbbtest.Server, 3)
+	pool := bbb.NewPool()
+	for k := range servers {
+		servers[k] = bbbtest.NewServer("secret")
+		defer servers[k].Close()
+		pool.Add(fmt.Sprintf("node%d", k), servers[k].NewClient())
+	}
+	ctx := context.Background()
+
+	if _, err := servers[0].NewClient().Create("busy", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		servers[0].AddAttendee("busy", bbb.Attendee{UserId: name, Name: name})
+	}
+	if err := pool.Refresh(ctx); nil != err {
+		t.Fatal(err)
+	}
+	if nodes := pool.Nodes(); 4 != nodes[0].Load() || 0 != nodes[1].Load() || !nodes[2].Healthy {
+		t.Errorf("unexpected nodes: %#v", nodes)
+	}
+
+	for k, id := range []string{"a", "b"} {
+		if _, err := pool.CreateWithContext(ctx, id, bbb.EmptyOptions); nil != err {
+			t.Fatal(err)
+		}
+		if name, _ := pool.Node(id); fmt.Sprintf("node%d", k+1) != name {
+			t.Errorf("expected %s on node%d, got %q", id, k+1, name)
+		}
+	}
+	servers[1].Fail("", &bbbtest.Failure{StatusCode: http.StatusServiceUnavailable})
+	if _, err := pool.CreateWithContext(ctx, "c", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	if name, _ := pool.Node("c"); "node2" != name || pool.Nodes()[1].Healthy {
+		t.Errorf("expected c on node2 and node1 unhealthy, got %q %#v", name, pool.Nodes()[1])
+	}
+	servers[1].Fail("", nil)
+
+	if u := pool.JoinURL("Alice", "a", "", &bbb.JoinOptions{Role: bbb.RoleViewer}); !strings.HasPrefix(u, servers[1].URL) {
+		t.Errorf("unexpected join URL: %s", u)
+	}
+	if m, err := pool.MeetingInfoWithContext(ctx, "busy", ""); nil != err || 3 != m.NumUsers {
+		t.Errorf("unexpected meeting info: %v (%v)", m, err)
+	}
+	if _, err := pool.MeetingInfoWithContext(ctx, "nope", ""); !bbb.IsNotFound(err) {
+		t.Errorf("expected notFound, got %v", err)
+	}
+	if err := pool.Refresh(ctx); nil != err || !pool.Nodes()[1].Healthy {
+		t.Errorf("expected node1 healthy after refresh (%v)", err)
+	}
+	servers[2].Fail("getMeetings", &bbbtest.Failure{StatusCode: http.StatusServiceUnavailable})
+	if _, err := pool.MeetingInfoWithContext(ctx, "nope", ""); !bbb.IsNotFound(err) || !pool.Nodes()[2].Healthy {
+		t.Errorf("expected notFound without refresh, got %v %#v", err, pool.Nodes()[2])
+	}
+	servers[2].Fail("getMeetings", nil)
+	if err := pool.EndWithContext(ctx, "a", "", nil); nil != err {
+		t.Fatal(err)
+	}
+	if _, found := servers[1].Meeting("a"); found {
+		t.Error("meeting a not ended on node1")
+	}
+	if _, found := pool.Node("a"); found {
+		t.Error("meeting a still placed")
+	}
+	if meetings, err := pool.MeetingsWithContext(ctx); nil != err || 3 != len(meetings) {
+		t.Errorf("unexpected meetings: %v (%v)", meetings, err)
+	}
+
+	for k, server := range servers {
+		server.AddRecording(bbb.Recording{
+			RecordId:  fmt.Sprintf("r%d", k),
+			MeetingId: "a",
+			StartTime: time.Unix(int64(1530718721-k), 0),
+		})
+	}
+	recordings, err := pool.RecordingsWithContext(ctx, nil)
+	if nil != err || 3 != len(recordings) || "r2" != recordings[0].RecordId || "r0" != recordings[2].RecordId {
+		t.Errorf("unexpected recordings: %v (%v)", recordings, err)
+	}
+	page, err := pool.RecordingsPageWithContext(ctx, &bbb.RecordingsOptions{Offset: 1, Limit: 1})
+	if nil != err || 3 != page.Total || 1 != len(page.Recordings) || "r1" != page.Recordings[0].RecordId {
+		t.Errorf("unexpected page: %v (%v)", page, err)
+	}
+	if ok, err := pool.PublishRecordingsWithContext(ctx, []string{"r0", "r2"}, true); nil != err || !ok {
+		t.Errorf("expected published, got %v (%v)", ok, err)
+	}
+	if r, _ := servers[2].Recording("r2"); !r.Published {
+		t.Errorf("r2 not published: %#v", r)
+	}
+	if _, err := pool.PublishRecordingsWithContext(ctx, []string{"r0", "missing"}, true); !bbb.IsNotFound(err) {
+		t.Errorf("expected notFound, got %v", err)
+	}
+	results, err := pool.UpdateRecordingsWithContext(ctx, []string{"r1", "missing"}, map[string]string{"name": "One"})
+	if nil != err || nil != results["r1"] || !bbb.IsNotFound(results["missing"]) {
+		t.Errorf("unexpected update results: %v (%v)", results, err)
+	}
+	if r, _ := servers[1].Recording("r1"); "One" != r.Metadata["name"] {
+		t.Errorf("r1 not updated: %#v", r)
+	}
+
+	servers[1].Fail("getRecordings", &bbbtest.Failure{StatusCode: http.StatusServiceUnavailable, Times: 1})
+	if _, err := pool.PublishRecordingsWithContext(ctx, []string{"r1"}, false); nil == err || bbb.IsNotFound(err) {
+		t.Errorf("expected node1's error, got %v", err)
+	}
+	pool.SetState("node2", bbb.NodeDisabled)
+	if ok, err := pool.DeleteRecordingsWithContext(ctx, []string{"r2"}); nil != err || !ok {
+		t.Errorf("expected r2 deleted on disabled node2, got %v (%v)", ok, err)
+	}
+}
+
+func TestPoolRecordingsPage(t *testing.T) {
+	servers := make([]*bbbtest.Server, 2)
+	apis := make([]*countingAPI, len(servers))
+	pool := bbb.NewPool()
+	for k := range servers {
+		servers[k] = bbbtest.NewServer("secret")
+		defer servers[k].Close()
+		for i := 0; i < 10; i++ {
+			servers[k].AddRecording(bbb.Recording{
+				RecordId:  fmt.Sprintf("r%02d", 2*i+k),
+				MeetingId: "a",
+				StartTime: time.Unix(int64(1530718721+2*i+k), 0),
+			})
+		}
+		apis[k] = &countingAPI{API: servers[k].NewClient()}
+		pool.Add(fmt.Sprintf("node%d", k), apis[k])
+	}
+	ctx := context.Background()
+
+	page, err := pool.RecordingsPageWithContext(ctx, &bbb.RecordingsOptions{Offset: 5, Limit: 3})
+	if nil != err || 20 != page.Total || 3 != len(page.Recordings) || "r05" != page.Recordings[0].RecordId || "r07" != page.Recordings[2].RecordId {
+		t.Errorf("unexpected page: %v (%v)", page, err)
+	}
+
+	apis[0].recordings, apis[1].recordings = 0, 0
+	it := bbb.NewRecordingIterator(ctx, pool, &bbb.RecordingsOptions{Limit: 3})
+	ids := []string{}
+	for it.Next() {
+		ids = append(ids, it.Recording().RecordId)
+	}
+	if nil != it.Err() || 20 != len(ids) || "r00" != ids[0] || "r19" != ids[19] {
+		t.Errorf("unexpected iteration: %v (%v)", ids, it.Err())
+	}
+	for k, api := range apis {
+		if api.recordings > 21 {
+			t.Errorf("node%d: fetched %d recordings to page through 10", k, api.recordings)
+		}
+	}
+
+	servers[1].Fail("getRecordings", &bbbtest.Failure{StatusCode: http.StatusServiceUnavailable})
+	if _, err := pool.RecordingsPageWithContext(ctx, &bbb.RecordingsOptions{Limit: 3}); nil == err {
+		t.Error("expected error for failing node1")
+	}
+}
+
+func TestPoolFailover(t *testing.T) {
+	servers := make([]*bbbtest.Server, 2)
+	pool := bbb.NewPool()
+	for k := range servers {
+		servers[k] = bbbtest.NewServer("secret")
+		defer servers[k].Close()
+		pool.Add(fmt.Sprintf("node%d", k), servers[k].NewClient())
+	}
+	ctx := context.Background()
+	for _, id := range []string{"room", "other"} {
+		if _, err := pool.CreateWithContext(ctx, id, bbb.EmptyOptions); nil != err {
+			t.Fatal(err)
+		}
+	}
+	if name, _ := pool.Node("other"); "node1" != name {
+		t.Fatalf("expected other on node1, got %q", name)
+	}
+
+	servers[0].Fail("", &bbbtest.Failure{StatusCode: http.StatusServiceUnavailable})
+	if _, err := pool.CreateWithContext(ctx, "room", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	if name, _ := pool.Node("room"); "node1" != name {
+		t.Errorf("room not failed over to node1, got %q", name)
+	}
+	pool.EndWithContext(ctx, "room", "", nil)
+	pool.SetState("node1", bbb.NodeDraining)
+	servers[0].Fail("", nil)
+	pool.Refresh(ctx)
+	if _, err := pool.CreateWithContext(ctx, "room", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	if name, _ := pool.Node("room"); "node0" != name {
+		t.Errorf("room not placed on node0 again, got %q", name)
+	}
+
+	servers[0].Fail("", &bbbtest.Failure{StatusCode: http.StatusServiceUnavailable})
+	for i := 0; i < 3; i++ {
+		pool.Refresh(ctx)
+	}
+	if name, found := pool.Node("room"); found {
+		t.Errorf("room still placed on failing %s", name)
+	}
+}
+
+func TestPoolCreateDocuments(t *testing.T) {
+	servers := make([]*bbbtest.Server, 2)
+	pool := bbb.NewPool()
+	for k := range servers {
+		servers[k] = bbbtest.NewServer("secret")
+		defer servers[k].Close()
+		pool.Add(fmt.Sprintf("node%d", k), servers[k].NewClient())
+	}
+	servers[0].Fail("create", &bbbtest.Failure{StatusCode: http.StatusServiceUnavailable, Times: 1})
+	options := &bbb.CreateOptions{Documents: []bbb.ConfigXML_Document{
+		bbb.NewDocumentReader("slides.pdf", "application/pdf", strings.NewReader("%PDF-1.4")),
+	}}
+	if _, err := pool.CreateWithContext(context.Background(), "docs", options); nil == err {
+		t.Error("expected create with a streamed document not to be retried")
+	}
+	if _, found := servers[1].Meeting("docs"); found {
+		t.Error("meeting created on node1 without its document")
+	}
+}
+
+func TestPoolStates(t *testing.T) {
+	servers := make([]*bbbtest.Server, 2)
+	pool := bbb.NewPool()
+	for k := range servers {
+		servers[k] = bbbtest.NewServer("secret")
+		defer servers[k].Close()
+		pool.Add(fmt.Sprintf("node%d", k), servers[k].NewClient())
+	}
+	if err := pool.Add("node0", servers[1].NewClient()); nil == err || 2 != len(pool.Nodes()) {
+		t.Error("expected error for duplicate node")
+	}
+	ctx := context.Background()
+	if _, err := servers[1].NewClient().Create("x", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	if _, err := pool.CreateWithContext(ctx, "x", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	if name, _ := pool.Node("x"); "node1" != name {
+		t.Errorf("running meeting x placed on %q", name)
+	}
+	if _, found := servers[0].Meeting("x"); found {
+		t.Error("meeting x created twice")
+	}
+	if err := pool.SetState("node0", bbb.NodeDraining); nil != err {
+		t.Fatal(err)
+	}
+	if nodes := pool.Nodes(); nodes[0].SafeToShutDown() {
+		t.Error("draining node0 safe to shut down before a refresh")
+	}
+	for _, id := range []string{"a", "b"} {
+		if _, err := pool.CreateWithContext(ctx, id, bbb.EmptyOptions); nil != err {
+			t.Fatal(err)
+		}
+		if name, _ := pool.Node(id); "node1" != name {
+			t.Errorf("meeting %s placed on %q", id, name)
+		}
+	}
+	pool.Refresh(ctx)
+	if nodes := pool.Nodes(); !nodes[0].SafeToShutDown() || nodes[1].SafeToShutDown() {
+		t.Errorf("unexpected shutdown states: %#v", nodes)
+	}
+
+	servers[1].AddAttendee("a", bbb.Attendee{UserId: "1", Name: "Alice"})
+	if err := pool.SetState("node1", bbb.NodeDisabled); nil != err {
+		t.Fatal(err)
+	}
+	if _, err := pool.CreateWithContext(ctx, "c", bbb.EmptyOptions); bbb.ErrNoHealthyNode != err {
+		t.Errorf("expected ErrNoHealthyNode, got %v", err)
+	}
+	if m, err := pool.MeetingInfoWithContext(ctx, "a", ""); nil != err || 1 != m.NumUsers {
+		t.Errorf("meeting a not routed to disabled node1: %v (%v)", m, err)
+	}
+	pool.Refresh(ctx)
+	if nodes := pool.Nodes(); 1 != nodes[1].Running || nodes[1].SafeToShutDown() {
+		t.Errorf("disabled node1 with a running meeting safe to shut down: %#v", nodes[1])
+	}
+	if err := pool.EndWithContext(ctx, "a", "", nil); nil != err {
+		t.Fatal(err)
+	}
+	pool.Refresh(ctx)
+	if nodes := pool.Nodes(); !nodes[1].SafeToShutDown() {
+		t.Errorf("expected disabled node1 safe to shut down: %#v", nodes[1])
+	}
+	pool.SetState("node0", bbb.NodeActive)
+	if _, err := pool.CreateWithContext(ctx, "b", bbb.EmptyOptions); nil != err {
+		t.Fatal(err)
+	}
+	if name, _ := pool.Node("b"); "node0" != name {
+		t.Errorf("idle meeting b kept on disabled node1, got %q", name)
+	}
+	if err := pool.SetState("node2", bbb.NodeActive); nil == err {
+		t.Error("expected error for unknown node")
+	}
+	if s, err := bbb.ParseNodeState("draining"); nil != err || bbb.NodeDraining != s {
+		t.Errorf("unexpected state: %v (%v)", s, err)
+	}
+	if _, err := bbb.ParseNodeState("paused"); nil == err {
+		t.Error("expected error for invalid state")
+	}
+}
@@ -48,10 +48,22 @@ type ConfigXML_BwMon struct {
 	Application string `json:"application,omitempty" xml:"application,attr,omitempty"`
 }
 
+// ConfigXML_Document is a presentation, either downloaded by the server from
+// Url or sent inline. Inline documents are created by NewDocumentFile and
+// NewDocumentReader, or from Value, and are sent base64 encoded.
 type ConfigXML_Document struct {
-	Name  string `json:"name,omitempty" xml:"name,attr,omitempty"`
-	Url   string `json:"url,omitempty"  xml:"url,attr,omitempty"`
-	Value []byte `json:"name,omitempty" xml:",chardata"`
+	Name         string `json:"name,omitempty" xml:"name,attr,omitempty"`
+	Url          string `json:"url,omitempty"  xml:"url,attr,omitempty"`
+	Filename     string `json:"filename,omitempty" xml:"filename,attr,omitempty"`
+	Downloadable bool   `json:"downloadable,omitempty" xml:"downloadable,attr,omitempty"`
+	Removable    *bool  `json:"removable,omitempty" xml:"removable,attr,omitempty"`
+	Current      bool   `json:"current,omitempty" xml:"current,attr,omitempty"`
+	Value        []byte `json:"value,omitempty" xml:",chardata"`
+	ContentType  string `json:"contentType,omitempty" xml:"-"`
+
+	reader io.Reader
+	path   string
+	size   int64
 }
 
 type ConfigXML_Help struct {
@@ -0,0 +1,205 @@
+package bbb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// DefaultMaxDocumentSize matches the default maxFileSizeUpload of bbb-web.
+const DefaultMaxDocumentSize = 30 << 20
+
+var ErrDocumentTooLarge = errors.New("document exceeds the size limit")
+
+// NewDocumentURL returns a document the server downloads from u.
+func NewDocumentURL(u, filename string) ConfigXML_Document {
+	return ConfigXML_Document{Url: u, Filename: filename}
+}
+
+// NewDocumentFile returns an inline document read from the file at path
+// when the request is sent.
+func NewDocumentFile(path string) (ConfigXML_Document, error) {
+	fi, err := os.Stat(path)
+	if nil != err {
+		return ConfigXML_Document{}, err
+	}
+	if fi.IsDir() {
+		return ConfigXML_Document{}, fmt.Errorf("bbb: %s is a directory", path)
+	}
+	return ConfigXML_Document{
+		Name:        filepath.Base(path),
+		ContentType: mime.TypeByExtension(filepath.Ext(path)),
+		path:        path,
+		size:        fi.Size(),
+	}, nil
+}
+
+// NewDocumentReader returns an inline document streamed from r. If name has
+// no extension, one is derived from contentType, since the server uses it to
+// pick a converter. The document can be sent only once, as r is consumed.
+func NewDocumentReader(name, contentType string, r io.Reader) ConfigXML_Document {
+	if "" == filepath.Ext(name) && "" != contentType {
+		if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+			name += exts[0]
+		}
+	}
+	return ConfigXML_Document{Name: name, ContentType: contentType, reader: r, size: -1}
+}
+
+// InsertDocument adds presentations to a running meeting. As with create,
+// documents are downloaded by the server from their Url or sent inline.
+func (b3 *BigBlueButton) InsertDocument(meetingID string, docs []ConfigXML_Document) error {
+	return b3.InsertDocumentWithContext(context.Background(), meetingID, docs)
+}
+
+func (b3 *BigBlueButton) InsertDocumentWithContext(ctx context.Context, meetingID string, docs []ConfigXML_Document) error {
+	action := "insertDocument"
+	if len(docs) < 1 {
+		return newMissingParamError(action, "Document")
+	}
+	body, err := b3.documentsBody(docs)
+	if nil != err {
+		return err
+	}
+	defer body.Close()
+	u := b3.makeURL(action, url.Values{"meetingID": {meetingID}})
+	res, err := b3.post(ctx, u.String(), "text/xml", body)
+	if nil != err {
+		return err
+	}
+	defer closeResponse(res)
+	return loadResponse(res, action, &responseXML{})
+}
+
+func (doc *ConfigXML_Document) inline() bool {
+	return nil != doc.reader || "" != doc.path || len(doc.Value) > 0
+}
+
+func (b3 *BigBlueButton) maxDocumentSize() int64 {
+	if b3.MaxDocumentSize > 0 {
+		return b3.MaxDocumentSize
+	}
+	return DefaultMaxDocumentSize
+}
+
+// documentsBody returns the <modules> XML for the given documents. Inline
+// documents are base64 encoded while the body is read, so that large files
+// are never held in memory.
+func (b3 *BigBlueButton) documentsBody(docs []ConfigXML_Document) (io.ReadCloser, error) {
+	limit := b3.maxDocumentSize()
+	sources, files := make([]io.Reader, len(docs)), []*os.File{}
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	for k := range docs {
+		doc := &docs[k]
+		switch {
+		case "" != doc.path:
+			if doc.size > limit {
+				closeAll()
+				return nil, fmt.Errorf("bbb: %s: %w", doc.Name, ErrDocumentTooLarge)
+			}
+			f, err := os.Open(doc.path)
+			if nil != err {
+				closeAll()
+				return nil, err
+			}
+			sources[k], files = f, append(files, f)
+		case nil != doc.reader:
+			sources[k] = doc.reader
+		case len(doc.Value) > int(limit):
+			closeAll()
+			return nil, fmt.Errorf("bbb: %s: %w", doc.Name, ErrDocumentTooLarge)
+		}
+	}
+
+	body, w := io.Pipe()
+	go func() {
+		defer closeAll()
+		w.CloseWithError(writeDocuments(w, docs, sources, limit))
+	}()
+	return body, nil
+}
+
+func writeDocuments(w io.Writer, docs []ConfigXML_Document, sources []io.Reader, limit int64) error {
+	if _, err := io.WriteString(w, `<modules><module name="presentation">`); nil != err {
+		return err
+	}
+	for k := range docs {
+		doc := &docs[k]
+		if err := writeDocumentStart(w, doc); nil != err {
+			return err
+		}
+		if doc.inline() {
+			enc := base64.NewEncoder(base64.StdEncoding, w)
+			var err error
+			if nil != sources[k] {
+				err = copyLimited(enc, sources[k], limit, doc.Name)
+			} else {
+				_, err = enc.Write(doc.Value)
+			}
+			if nil == err {
+				err = enc.Close()
+			}
+			if nil != err {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "</document>"); nil != err {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "</module></modules>")
+	return err
+}
+
+func writeDocumentStart(w io.Writer, doc *ConfigXML_Document) error {
+	attr := func(name, value string) string {
+		if "" == value {
+			return ""
+		}
+		return " " + name + `="` + escapeAttr(value) + `"`
+	}
+	s := "<document"
+	if doc.inline() {
+		s += attr("name", doc.Name)
+	} else {
+		s += attr("url", doc.Url) + attr("filename", doc.Filename)
+	}
+	if doc.Downloadable {
+		s += ` downloadable="true"`
+	}
+	if nil != doc.Removable {
+		s += attr("removable", strconv.FormatBool(*doc.Removable))
+	}
+	if doc.Current {
+		s += ` current="true"`
+	}
+	_, err := io.WriteString(w, s+">")
+	return err
+}
+
+func escapeAttr(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func copyLimited(w io.Writer, r io.Reader, limit int64, name string) error {
+	n, err := io.Copy(w, io.LimitReader(r, limit+1))
+	if nil == err && n > limit {
+		err = fmt.Errorf("bbb: %s: %w", name, ErrDocumentTooLarge)
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package bbb
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// APIError is returned for every API call the server did not answer with
+// returncode SUCCESS, or answered with a non-2xx HTTP status.
+type APIError struct {
+	Action     string
+	ReturnCode string
+	MessageKey string
+	Message    string
+	StatusCode int
+}
+
+func (err *APIError) Error() string {
+	status := err.ReturnCode
+	if "" != err.MessageKey {
+		if "" != status {
+			status += " "
+		}
+		status += err.MessageKey
+	}
+	s := "bbb: " + err.Action
+	if "" != status {
+		s += ": " + status
+	}
+	if "" != err.Message {
+		s += ": " + err.Message
+	}
+	if err.StatusCode >= 300 || (err.StatusCode > 0 && "" == err.ReturnCode) {
+		s += " (HTTP " + strconv.Itoa(err.StatusCode) + ")"
+	}
+	return s
+}
+
+func newHTTPError(action string, status int) *APIError {
+	return &APIError{
+		Action:     action,
+		Message:    http.StatusText(status),
+		StatusCode: status,
+	}
+}
+
+func newMissingParamError(action, param string) *APIError {
+	return &APIError{
+		Action:     action,
+		ReturnCode: "FAILED",
+		MessageKey: "missingParam" + param,
+		Message:    "You must specify a " + param + ".",
+	}
+}
+
+func IsNotFound(err error) bool {
+	return hasMessageKey(err, "notFound", "invalidMeetingIdentifier", "invalidMeetingId",
+		"destroyMissingHook")
+}
+
+func IsChecksumError(err error) bool {
+	return hasMessageKey(err, "checksumError")
+}
+
+func IsDuplicate(err error) bool {
+	return hasMessageKey(err, "idNotUnique", "duplicateWarning")
+}
+
+func hasMessageKey(err error, keys ...string) bool {
+	var e *APIError
+	if errors.As(err, &e) {
+		for _, key := range keys {
+			if key == e.MessageKey {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// transient reports whether err is a transport or server error, which may
+// go away when the request is retried.
+func transient(err error) bool {
+	var e *APIError
+	return !errors.As(err, &e) || e.StatusCode >= 500
+}
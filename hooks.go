@@ -0,0 +1,118 @@
+package bbb
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// Hooks is a client for the bbb-webhooks API that is served next to the
+// regular API (hooks/create, hooks/list, hooks/destroy).
+type Hooks struct {
+	b3 *BigBlueButton
+}
+
+func (b3 *BigBlueButton) Hooks() *Hooks {
+	return &Hooks{b3}
+}
+
+// Hook is a registered webhook subscription. A hook without MeetingId is a
+// global hook that receives the events of all meetings.
+type Hook struct {
+	Id          string
+	CallbackURL string
+	MeetingId   string
+	Permanent   bool
+	Raw         bool
+}
+
+type HookOptions struct {
+	MeetingId string `json:"meetingID"`
+	GetRaw    bool   `json:"getRaw"`
+
+	// EventId optionally restricts the hook to a comma separated list of
+	// event ids, e.g. "meeting-created,meeting-ended".
+	EventId string `json:"eventID"`
+}
+
+func (opt *HookOptions) Values() url.Values {
+	return reflectOptionValues(reflect.ValueOf(*opt), true, nil)
+}
+
+// Create registers a hook. If there is one for the callback URL and meeting
+// already, the server answers with a duplicateWarning and the existing ID.
+func (h *Hooks) Create(callbackURL string, options OptionEncoder) (*Hook, error) {
+	return h.CreateWithContext(context.Background(), callbackURL, options)
+}
+
+func (h *Hooks) CreateWithContext(ctx context.Context, callbackURL string, options OptionEncoder) (*Hook, error) {
+	action := "hooks/create"
+	params := options.Values()
+	u := h.b3.makeURL(action, mergeUrlValues(url.Values{"callbackURL": {callbackURL}}, params))
+	res, err := h.b3.get(ctx, u.String())
+	if nil != err {
+		return nil, err
+	}
+	defer closeResponse(res)
+	var response hookResponse
+	if err := loadResponse(res, action, &response); nil != err {
+		return nil, err
+	}
+	hook := response.hook()
+	hook.CallbackURL = callbackURL
+	hook.MeetingId = params.Get("meetingID")
+	return hook, nil
+}
+
+// List returns all hooks, or only the hooks of the given meeting (including
+// global hooks) if meetingID is not empty.
+func (h *Hooks) List(meetingID string) ([]*Hook, error) {
+	return h.ListWithContext(context.Background(), meetingID)
+}
+
+func (h *Hooks) ListWithContext(ctx context.Context, meetingID string) ([]*Hook, error) {
+	action := "hooks/list"
+	q := url.Values{}
+	if "" != meetingID {
+		q.Set("meetingID", meetingID)
+	}
+	u := h.b3.makeURL(action, q)
+	res, err := h.b3.get(ctx, u.String())
+	if nil != err {
+		return nil, err
+	}
+	defer closeResponse(res)
+	return loadHooksResponse(res, action)
+}
+
+func (h *Hooks) Destroy(id string) (bool, error) {
+	return h.DestroyWithContext(context.Background(), id)
+}
+
+func (h *Hooks) DestroyWithContext(ctx context.Context, id string) (bool, error) {
+	action := "hooks/destroy"
+	u := h.b3.makeURL(action, url.Values{"hookID": {id}})
+	res, err := h.b3.get(ctx, u.String())
+	if nil != err {
+		return false, err
+	}
+	defer closeResponse(res)
+	var response hookDestroyResponse
+	if err := loadResponse(res, action, &response); nil != err {
+		return false, err
+	}
+	return bool(response.Removed), nil
+}
+
+func loadHooksResponse(r *http.Response, action string) ([]*Hook, error) {
+	var response hooksResponse
+	if err := loadResponse(r, action, &response); nil != err {
+		return nil, err
+	}
+	hooks := make([]*Hook, len(response.Hooks))
+	for k := range response.Hooks {
+		hooks[k] = response.Hooks[k].hook()
+	}
+	return hooks, nil
+}
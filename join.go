@@ -0,0 +1,73 @@
+package bbb
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// JoinRequest is a join URL decoded by ParseJoinURL. The role of the user
+// follows from Password or, on recent servers, Options.Role. Parameters
+// JoinOptions has no field for are kept in Extra.
+type JoinRequest struct {
+	Name      string
+	MeetingID string
+	Password  string
+	Options   JoinOptions
+	Extra     url.Values
+	Checksum  string
+
+	query string
+}
+
+// ParseJoinURL decodes a join URL, the inverse of JoinURL. The checksum is
+// not checked, see Verify.
+func ParseJoinURL(rawurl string) (*JoinRequest, error) {
+	u, err := url.Parse(rawurl)
+	if nil != err {
+		return nil, err
+	}
+	if "join" != path.Base(u.Path) {
+		return nil, fmt.Errorf("bbb: not a join URL: %s", u.Path)
+	}
+	params, err := url.ParseQuery(u.RawQuery)
+	if nil != err {
+		return nil, err
+	}
+	r := &JoinRequest{
+		Name:      params.Get("fullName"),
+		MeetingID: params.Get("meetingID"),
+		Password:  params.Get("password"),
+		Checksum:  params.Get("checksum"),
+		query:     u.RawQuery,
+	}
+	if "" == r.MeetingID {
+		return nil, newMissingParamError("join", "MeetingID")
+	}
+	for _, k := range []string{"fullName", "meetingID", "password", "checksum"} {
+		params.Del(k)
+	}
+	for k := range params {
+		if strings.HasPrefix(k, "userdata-") {
+			if nil == r.Options.UserData {
+				r.Options.UserData = map[string]string{}
+			}
+			r.Options.UserData[k[9:]] = params.Get(k)
+			params.Del(k)
+		}
+	}
+	r.Extra, err = decodeOptionValues(reflect.ValueOf(&r.Options).Elem(), params)
+	if nil != err {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Verify checks the checksum of the join URL against the secrets, see
+// VerifyChecksum.
+func (r *JoinRequest) Verify(secrets ...string) (ChecksumAlgorithm, error) {
+	_, a, err := VerifyChecksum("join", r.query, secrets...)
+	return a, err
+}
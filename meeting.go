@@ -1,11 +1,13 @@
 package bbb
 
 import (
+	"sort"
 	"time"
 )
 
 type Meeting struct {
 	Id          string
+	InternalId  string
 	Name        string
 	CreateTime  time.Time
 	VoiceBridge int
@@ -20,10 +22,50 @@ type Meeting struct {
 	NumMod      int
 	MaxUsers    int
 	Attendees   []Attendee
+	Metadata    map[string]string
+
+	// Breakout rooms refer to each other by InternalId.
+	IsBreakout      bool
+	ParentMeetingID string
+	Sequence        int
+	FreeJoin        bool
+	BreakoutRooms   []string
+}
+
+func (m *Meeting) breakoutRooms(meetings []*Meeting) []*Meeting {
+	rooms := []*Meeting{}
+	for _, room := range meetings {
+		if !room.IsBreakout || room == m {
+			continue
+		}
+		if "" != room.ParentMeetingID && room.ParentMeetingID == m.InternalId {
+			rooms = append(rooms, room)
+			continue
+		}
+		for _, id := range m.BreakoutRooms {
+			if id == room.InternalId {
+				rooms = append(rooms, room)
+				break
+			}
+		}
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].Sequence < rooms[j].Sequence
+	})
+	return rooms
 }
 
 type Attendee struct {
-	UserId string
-	Name   string
-	Role   string
+	UserId          string
+	InternalUserId  string
+	Name            string
+	Role            string
+	IsPresenter     bool
+	IsListeningOnly bool
+	HasJoinedVoice  bool
+	HasVideo        bool
+	ClientType      string
+
+	// CustomData holds the userdata-* values passed on join.
+	CustomData map[string]string
 }
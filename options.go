@@ -1,6 +1,7 @@
 package bbb
 
 import (
+	"fmt"
 	"net/url"
 	"reflect"
 	"strconv"
@@ -23,15 +24,171 @@ type CreateOptions struct {
 	Record          bool          `json:"record"`
 	Duration        time.Duration `json:"duration"`
 
-	Documents []ConfigXML_Document `json:documents`
+	// Moderation and recording; nil flags use the server defaults from
+	// bbb-web.properties, use Bool(false) to turn a flag off explicitly.
+	GuestPolicy             string `json:"guestPolicy"`
+	MuteOnStart             *bool  `json:"muteOnStart"`
+	AllowModsToUnmuteUsers  *bool  `json:"allowModsToUnmuteUsers"`
+	AllowModsToEjectCameras *bool  `json:"allowModsToEjectCameras"`
+	WebcamsOnlyForModerator *bool  `json:"webcamsOnlyForModerator"`
+	UserCameraCap           *int   `json:"userCameraCap"`
+	MeetingCameraCap        *int   `json:"meetingCameraCap"`
+	AutoStartRecording      *bool  `json:"autoStartRecording"`
+	AllowStartStopRecording *bool  `json:"allowStartStopRecording"`
+	NotifyRecordingIsOn     *bool  `json:"notifyRecordingIsOn"`
+	ModeratorOnlyMessage    string `json:"moderatorOnlyMessage"`
+	EndWhenNoModerator      *bool  `json:"endWhenNoModerator"`
+	EndWhenNoModeratorDelay *int   `json:"endWhenNoModeratorDelayInMinutes"`
+	MeetingKeepEvents       *bool  `json:"meetingKeepEvents"`
+	MeetingLayout           string `json:"meetingLayout"`
+
+	// DisabledFeatures is sent as a comma separated list, e.g.
+	// "breakoutRooms", "chat", "sharedNotes", "polls", "screenshare".
+	DisabledFeatures []string `json:"disabledFeatures"`
+
+	// Lock settings
+	LockSettingsDisableCam             *bool `json:"lockSettingsDisableCam"`
+	LockSettingsDisableMic             *bool `json:"lockSettingsDisableMic"`
+	LockSettingsDisablePrivateChat     *bool `json:"lockSettingsDisablePrivateChat"`
+	LockSettingsDisablePublicChat      *bool `json:"lockSettingsDisablePublicChat"`
+	LockSettingsDisableNotes           *bool `json:"lockSettingsDisableNotes"`
+	LockSettingsHideUserList           *bool `json:"lockSettingsHideUserList"`
+	LockSettingsHideViewersCursor      *bool `json:"lockSettingsHideViewersCursor"`
+	LockSettingsLockedLayout           *bool `json:"lockSettingsLockedLayout"`
+	LockSettingsLockOnJoin             *bool `json:"lockSettingsLockOnJoin"`
+	LockSettingsLockOnJoinConfigurable *bool `json:"lockSettingsLockOnJoinConfigurable"`
+
+	// Branding
+	BannerText  string `json:"bannerText"`
+	BannerColor string `json:"bannerColor"`
+	Logo        string `json:"logo"`
+	Copyright   string `json:"copyright"`
+
+	// Breakout rooms
+	IsBreakout      bool   `json:"isBreakout"`
+	ParentMeetingID string `json:"parentMeetingID"`
+	Sequence        uint   `json:"sequence"`
+	FreeJoin        bool   `json:"freeJoin"`
+
+	// Metadata is sent as meta_<key>=<value> and returned in the
+	// Metadata of getMeetingInfo, getMeetings and getRecordings.
+	Metadata map[string]string `json:"metadata"`
+
+	Documents []ConfigXML_Document `json:"documents"`
 }
 
+const (
+	GuestPolicyAlwaysAccept = "ALWAYS_ACCEPT"
+	GuestPolicyAlwaysDeny   = "ALWAYS_DENY"
+	GuestPolicyAskModerator = "ASK_MODERATOR"
+)
+
+const (
+	LayoutCustom            = "CUSTOM_LAYOUT"
+	LayoutSmart             = "SMART_LAYOUT"
+	LayoutPresentationFocus = "PRESENTATION_FOCUS"
+	LayoutVideoFocus        = "VIDEO_FOCUS"
+)
+
+const (
+	RoleModerator = "MODERATOR"
+	RoleViewer    = "VIEWER"
+)
+
 type JoinOptions struct {
 	CreateTime   time.Time `json:"createTime"`
 	UserId       string    `json:"userID"`
 	WebVoiceConf string    `json:"webVoiceConf"`
 	ConfigToken  string    `json:"configToken"`
 	AvatarURL    string    `json:"avatarURL"`
+
+	// Role (RoleModerator or RoleViewer) can be used instead of a password
+	// on BigBlueButton 2.4 and later; pass an empty password to JoinURL.
+	Role                 string `json:"role"`
+	Guest                bool   `json:"guest"`
+	ExcludeFromDashboard bool   `json:"excludeFromDashboard"`
+	ErrorRedirectUrl     string `json:"errorRedirectUrl"`
+
+	// Redirect defaults to true on the server; use Bool(false) to get an
+	// XML response with the session token instead.
+	Redirect *bool `json:"redirect"`
+
+	// UserData is sent as userdata-<key>=<value>.
+	UserData map[string]string `json:"userdata"`
+}
+
+// RecordingsOptions filter getRecordings. IDs and states are sent as comma
+// separated lists; Metadata is sent as meta_<key>=<value> and matches
+// recordings with equal metadata. Offset and Limit (at most 100) need
+// BigBlueButton 2.6 or later; older servers return all recordings.
+type RecordingsOptions struct {
+	MeetingIds []string          `json:"meetingID"`
+	RecordIds  []string          `json:"recordID"`
+	States     []string          `json:"state"`
+	Metadata   map[string]string `json:"metadata"`
+	Offset     uint              `json:"offset"`
+	Limit      uint              `json:"limit"`
+}
+
+func (opt *RecordingsOptions) Values() url.Values {
+	return mergeUrlValues(reflectOptionValues(reflect.ValueOf(*opt), true,
+		func(k string, _ reflect.Value) bool {
+			return "metadata" != k
+		}), metaValues(opt.Metadata))
+}
+
+type EndStrategy int
+
+const (
+	// EndNoWait returns as soon as the server accepted the request.
+	EndNoWait EndStrategy = iota
+
+	// EndWaitGone polls getMeetingInfo until the meeting is not found.
+	EndWaitGone
+
+	// EndWaitNotRunning polls isMeetingRunning until it reports false,
+	// which is sooner than EndWaitGone, as the meeting is kept for a while.
+	EndWaitNotRunning
+)
+
+// EndOptions select how End confirms that a meeting has ended. Waiting is
+// limited by the deadline of the context passed to EndWithContext, or by
+// MaxWait if it has none.
+type EndOptions struct {
+	Wait EndStrategy
+
+	// Interval is the first delay between polls, doubled after every poll
+	// up to MaxInterval; 500ms and 5s if 0.
+	Interval    time.Duration
+	MaxInterval time.Duration
+
+	// MaxWait is how long to wait without a context deadline; 1m if 0.
+	MaxWait time.Duration
+}
+
+func (opt *EndOptions) maxWait() time.Duration {
+	if opt.MaxWait > 0 {
+		return opt.MaxWait
+	}
+	return time.Minute
+}
+
+func (opt *EndOptions) interval() time.Duration {
+	if opt.Interval > 0 {
+		return opt.Interval
+	}
+	return 500 * time.Millisecond
+}
+
+func (opt *EndOptions) next(interval time.Duration) time.Duration {
+	max := opt.MaxInterval
+	if max <= 0 {
+		max = 5 * time.Second
+	}
+	if interval *= 2; interval > max {
+		return max
+	}
+	return interval
 }
 
 type OptionEncoder interface {
@@ -43,14 +200,36 @@ type emptyOptions struct{}
 func (opt *emptyOptions) Values() url.Values { return url.Values{} }
 
 func (opt *CreateOptions) Values() url.Values {
-	return reflectOptionValues(reflect.ValueOf(*opt), true,
+	return mergeUrlValues(reflectOptionValues(reflect.ValueOf(*opt), true,
 		func(k string, _ reflect.Value) bool {
-			return "documents" != k
-		})
+			return "documents" != k && "metadata" != k
+		}), metaValues(opt.Metadata))
 }
 
 func (opt *JoinOptions) Values() url.Values {
-	return reflectOptionValues(reflect.ValueOf(*opt), true, nil)
+	values := reflectOptionValues(reflect.ValueOf(*opt), true,
+		func(k string, _ reflect.Value) bool {
+			return "userdata" != k
+		})
+	for k, v := range opt.UserData {
+		values.Set("userdata-"+k, v)
+	}
+	return values
+}
+
+// Bool returns a pointer to v, for optional flags that have to be sent even
+// if false.
+func Bool(v bool) *bool {
+	return &v
+}
+
+// metaValues encodes meta as BigBlueButton meta_* parameters.
+func metaValues(meta map[string]string) url.Values {
+	values := url.Values{}
+	for k, v := range meta {
+		values.Set("meta_"+k, v)
+	}
+	return values
 }
 
 func reflectOptionValues(rv reflect.Value, skipFalse bool,
@@ -60,21 +239,8 @@ func reflectOptionValues(rv reflect.Value, skipFalse bool,
 		for i := 0; i < rv.NumField(); i++ {
 			if name, value := optionNameFromStructField(rv.Type().Field(i)),
 				rv.Field(i); nil == accept || accept(name, value) {
-				switch value.Kind() {
-				case reflect.Bool:
-					if value := value.Bool(); value || !skipFalse {
-						values.Set(name, strconv.FormatBool(value))
-					}
-				case reflect.String:
-					if value := value.String(); "" != value {
-						values.Set(name, value)
-					}
-				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-					values.Set(name, strconv.FormatInt(value.Int(), 10))
-				case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-					if value := value.Uint(); value > 0 {
-						values.Set(name, strconv.FormatUint(value, 10))
-					}
+				if value, ok := optionValue(value, skipFalse, false); ok {
+					values.Set(name, value)
 				}
 			}
 		}
@@ -82,6 +248,119 @@ func reflectOptionValues(rv reflect.Value, skipFalse bool,
 	return values
 }
 
+// optionValue encodes a single option value. Explicit values, those behind
+// a non-nil pointer, are sent even if they are the zero value.
+func optionValue(value reflect.Value, skipFalse, explicit bool) (string, bool) {
+	switch v := value.Interface().(type) {
+	case time.Time:
+		return strconv.FormatInt(v.UnixNano()/int64(time.Millisecond), 10), explicit || !v.IsZero()
+	case time.Duration:
+		return strconv.FormatInt(int64(v/time.Minute), 10), explicit || v > 0
+	}
+	switch value.Kind() {
+	case reflect.Ptr:
+		if !value.IsNil() {
+			return optionValue(value.Elem(), false, true)
+		}
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), explicit || value.Bool() || !skipFalse
+	case reflect.String:
+		return value.String(), explicit || "" != value.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), explicit || value.Uint() > 0
+	case reflect.Slice:
+		if reflect.String == value.Type().Elem().Kind() && value.Len() > 0 {
+			list := make([]string, value.Len())
+			for i := range list {
+				list[i] = value.Index(i).String()
+			}
+			return strings.Join(list, ","), true
+		}
+	}
+	return "", false
+}
+
+// decodeOptionValues sets the fields of the struct rv from values, the
+// inverse of reflectOptionValues. It returns the values matching no field.
+func decodeOptionValues(rv reflect.Value, values url.Values) (url.Values, error) {
+	rest := url.Values{}
+	for k, v := range values {
+		rest[k] = v
+	}
+	for i := 0; i < rv.NumField(); i++ {
+		name := optionNameFromStructField(rv.Type().Field(i))
+		if _, t := rest[name]; !t || reflect.Map == rv.Field(i).Kind() {
+			continue
+		}
+		if err := setOptionValue(rv.Field(i), rest.Get(name)); nil != err {
+			return rest, fmt.Errorf("bbb: invalid %s %q: %v", name, rest.Get(name), err)
+		}
+		delete(rest, name)
+	}
+	return rest, nil
+}
+
+// setOptionValue decodes a single option value encoded by optionValue.
+func setOptionValue(value reflect.Value, s string) error {
+	switch value.Interface().(type) {
+	case time.Time:
+		ms, err := strconv.ParseInt(s, 10, 64)
+		if nil == err {
+			value.Set(reflect.ValueOf(mstime(ms)))
+		}
+		return err
+	case time.Duration:
+		minutes, err := strconv.ParseInt(s, 10, 64)
+		if nil == err {
+			value.Set(reflect.ValueOf(time.Duration(minutes) * time.Minute))
+		}
+		return err
+	}
+	switch value.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(value.Type().Elem())
+		if err := setOptionValue(elem.Elem(), s); nil != err {
+			return err
+		}
+		value.Set(elem)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(s)
+		if nil != err {
+			return err
+		}
+		value.SetBool(v)
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(s, 10, value.Type().Bits())
+		if nil != err {
+			return err
+		}
+		value.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(s, 10, value.Type().Bits())
+		if nil != err {
+			return err
+		}
+		value.SetUint(v)
+	case reflect.Slice:
+		if reflect.String != value.Type().Elem().Kind() {
+			return fmt.Errorf("unsupported type %s", value.Type())
+		}
+		list := strings.Split(s, ",")
+		slice := reflect.MakeSlice(value.Type(), len(list), len(list))
+		for i, v := range list {
+			slice.Index(i).SetString(v)
+		}
+		value.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
 func optionNameFromStructField(s reflect.StructField) string {
 	if tag := s.Tag.Get("json"); tag != "" {
 		tag, _ := parseTag(tag)
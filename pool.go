@@ -0,0 +1,900 @@
+package bbb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Pool spreads meetings over several servers and implements API itself.
+// New meetings are created on the healthy node with the lowest load, the
+// number of meetings plus participants as of the last Refresh. Actions on a
+// meeting are routed to the node hosting it; recordings are aggregated over
+// all nodes.
+//
+// Nodes are considered healthy until a request fails with a transport error
+// or a 5xx status, and again after a successful Refresh. Only active nodes
+// get new meetings, see SetState.
+type Pool struct {
+	m          sync.RWMutex
+	nodes      []*poolNode
+	meetings   map[string]placement
+	refreshing sync.Mutex
+	refreshed  time.Time
+	cursor     recordingCursor
+}
+
+// PoolNode describes a server of a Pool as of the last Refresh. Running is
+// the number of running meetings among Meetings.
+type PoolNode struct {
+	Name     string
+	API      API
+	State    NodeState
+	Since    time.Time
+	Healthy  bool
+	Meetings int
+	Running  int
+	Users    int
+	Updated  time.Time
+	Err      error
+}
+
+// Load is the number of meetings and participants on the node.
+func (n PoolNode) Load() int {
+	return n.Meetings + n.Users
+}
+
+// SafeToShutDown reports whether the node is draining or disabled and had
+// no running meetings at a successful Refresh since.
+func (n PoolNode) SafeToShutDown() bool {
+	if NodeActive == n.State {
+		return false
+	}
+	return nil == n.Err && 0 == n.Running && n.Updated.After(n.Since)
+}
+
+// NodeState controls which requests a node of a Pool receives.
+type NodeState int
+
+const (
+	// NodeActive nodes get new meetings.
+	NodeActive NodeState = iota
+	// NodeDraining nodes keep their meetings but get no new ones.
+	NodeDraining
+	// NodeDisabled nodes get no new meetings either and are left out of
+	// meeting and recording listings, but still serve the meetings they host.
+	NodeDisabled
+)
+
+func (s NodeState) String() string {
+	switch s {
+	case NodeActive:
+		return "active"
+	case NodeDraining:
+		return "draining"
+	case NodeDisabled:
+		return "disabled"
+	}
+	return fmt.Sprintf("NodeState(%d)", int(s))
+}
+
+// ParseNodeState is the inverse of NodeState.String.
+func ParseNodeState(s string) (NodeState, error) {
+	for _, state := range []NodeState{NodeActive, NodeDraining, NodeDisabled} {
+		if s == state.String() {
+			return state, nil
+		}
+	}
+	return NodeActive, fmt.Errorf("bbb: invalid node state %q", s)
+}
+
+type poolNode struct {
+	PoolNode
+	failures int
+}
+
+// maxRefreshFailures is the number of Refreshes in a row a node may fail
+// before the pool forgets the meetings on it.
+const maxRefreshFailures = 3
+
+type placement struct {
+	node  *poolNode
+	since time.Time
+}
+
+var ErrNoHealthyNode = errors.New("bbb: no healthy node in pool")
+
+// MinLookupRefresh is the minimum time between the refreshes done to find
+// meetings the pool does not know yet.
+var MinLookupRefresh = time.Second
+
+var _ API = (*Pool)(nil)
+
+func NewPool() *Pool {
+	return &Pool{meetings: map[string]placement{}}
+}
+
+// Add adds a server to the pool under a unique name.
+func (p *Pool) Add(name string, api API) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	for _, n := range p.nodes {
+		if name == n.Name {
+			return fmt.Errorf("bbb: node %q already in pool", name)
+		}
+	}
+	p.nodes = append(p.nodes, &poolNode{PoolNode: PoolNode{Name: name, API: api, Since: time.Now(), Healthy: true}})
+	return nil
+}
+
+// SetState changes the state of the named node.
+func (p *Pool) SetState(name string, state NodeState) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	for _, n := range p.nodes {
+		if name != n.Name {
+			continue
+		}
+		if state != n.State {
+			n.State, n.Since = state, time.Now()
+		}
+		return nil
+	}
+	return fmt.Errorf("bbb: no node %q in pool", name)
+}
+
+// Nodes returns the state of all nodes, in the order they were added.
+func (p *Pool) Nodes() []PoolNode {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	nodes := make([]PoolNode, len(p.nodes))
+	for k, n := range p.nodes {
+		nodes[k] = n.PoolNode
+	}
+	return nodes
+}
+
+// Node returns the name of the node hosting the meeting, if known.
+func (p *Pool) Node(meetingID string) (string, bool) {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	if pl, t := p.meetings[meetingID]; t {
+		return pl.node.Name, true
+	}
+	return "", false
+}
+
+// Refresh polls getMeetings on all nodes to update their health, load and
+// the meetings they host. It returns ErrNoHealthyNode if all of them failed.
+func (p *Pool) Refresh(ctx context.Context) error {
+	p.m.RLock()
+	nodes := append([]*poolNode{}, p.nodes...)
+	p.m.RUnlock()
+
+	start := time.Now()
+	results := make([][]*Meeting, len(nodes))
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for k, n := range nodes {
+		wg.Add(1)
+		go func(k int, n *poolNode) {
+			defer wg.Done()
+			results[k], errs[k] = n.API.MeetingsWithContext(ctx)
+		}(k, n)
+	}
+	wg.Wait()
+
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.refreshed = start
+	healthy := 0
+	for k, n := range nodes {
+		n.Updated, n.Err = start, errs[k]
+		if nil != errs[k] {
+			if n.Healthy, n.failures = false, n.failures+1; n.failures >= maxRefreshFailures {
+				p.forget(n)
+			}
+			continue
+		}
+		healthy++
+		n.failures = 0
+		n.Healthy, n.Meetings, n.Running, n.Users = true, len(results[k]), 0, 0
+		hosted := map[string]bool{}
+		for _, m := range results[k] {
+			if m.Running {
+				n.Running++
+			}
+			n.Users += m.NumUsers
+			hosted[m.Id] = true
+			if pl, t := p.meetings[m.Id]; !t || pl.node != n && pl.since.Before(start) {
+				p.meetings[m.Id] = placement{n, start}
+			}
+		}
+		for id, pl := range p.meetings {
+			if pl.node == n && !hosted[id] && pl.since.Before(start) {
+				delete(p.meetings, id)
+			}
+		}
+	}
+	if 0 == healthy && len(nodes) > 0 {
+		return ErrNoHealthyNode
+	}
+	return nil
+}
+
+// Run refreshes the pool every interval until ctx is done.
+func (p *Pool) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.Refresh(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// enabled returns the nodes that are not disabled.
+func (p *Pool) enabled() []*poolNode {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	nodes := []*poolNode{}
+	for _, n := range p.nodes {
+		if NodeDisabled != n.State {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// candidates returns the healthy nodes ordered by load: the active ones, or
+// also the draining ones for requests that do not create meetings.
+func (p *Pool) candidates(draining bool) []*poolNode {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	nodes := []*poolNode{}
+	for _, n := range p.nodes {
+		if n.Healthy && (NodeActive == n.State || (draining && NodeDraining == n.State)) {
+			nodes = append(nodes, n)
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Load() < nodes[j].Load()
+	})
+	return nodes
+}
+
+// failed marks the node unhealthy if err is a transport or server error.
+func (p *Pool) failed(ctx context.Context, n *poolNode, err error) bool {
+	var apiErr *APIError
+	if nil == err || nil != ctx.Err() || (errors.As(err, &apiErr) && apiErr.StatusCode < 500) {
+		return false
+	}
+	p.m.Lock()
+	defer p.m.Unlock()
+	n.Healthy, n.Err = false, err
+	return true
+}
+
+// Forget drops the placement of the meeting, e.g. after it was ended
+// without the pool.
+func (p *Pool) Forget(meetingID string) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	delete(p.meetings, meetingID)
+}
+
+// unplace forgets that the meeting is on node n.
+func (p *Pool) unplace(id string, n *poolNode) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if pl, t := p.meetings[id]; t && pl.node == n {
+		delete(p.meetings, id)
+	}
+}
+
+// forget drops all placements on node n; p.m must be held.
+func (p *Pool) forget(n *poolNode) {
+	for id, pl := range p.meetings {
+		if pl.node == n {
+			delete(p.meetings, id)
+		}
+	}
+}
+
+func (p *Pool) place(id string, n *poolNode) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if pl, t := p.meetings[id]; !t || pl.node != n {
+		n.Meetings++
+	}
+	p.meetings[id] = placement{n, time.Now()}
+}
+
+// Lookup returns the node hosting the meeting, refreshing the pool once if
+// it is not known yet and the last refresh is older than MinLookupRefresh.
+// Unknown meetings yield a notFound APIError.
+func (p *Pool) Lookup(ctx context.Context, meetingID string) (PoolNode, error) {
+	n, err := p.lookup(ctx, "getMeetingInfo", meetingID)
+	if nil != err {
+		return PoolNode{}, err
+	}
+	return p.snapshot(n), nil
+}
+
+// Assign runs fn for the node hosting the meeting or, for a new meeting, for
+// the healthy nodes in order of load until it succeeds. The meeting is then
+// placed on that node. Meetings not known yet are looked up with a refresh
+// first, as in Lookup. Meetings on an unhealthy node, or on a node that is
+// not active and no longer runs them, are placed anew. Nodes failing with a
+// transport or server error are marked unhealthy; other errors are returned
+// right away.
+func (p *Pool) Assign(ctx context.Context, meetingID string, fn func(PoolNode) error) (PoolNode, error) {
+	return p.assign(ctx, meetingID, true, fn)
+}
+
+// assign is Assign, trying only the first node if retry is false.
+func (p *Pool) assign(ctx context.Context, meetingID string, retry bool, fn func(PoolNode) error) (PoolNode, error) {
+	p.m.RLock()
+	pl, known := p.meetings[meetingID]
+	p.m.RUnlock()
+	if !known {
+		p.refreshOnce(ctx)
+		p.m.RLock()
+		pl, known = p.meetings[meetingID]
+		p.m.RUnlock()
+	}
+	if known {
+		n := p.snapshot(pl.node)
+		use := n.Healthy
+		if use && NodeActive != n.State {
+			running, err := n.API.IsMeetingRunningWithContext(ctx, meetingID)
+			p.failed(ctx, pl.node, err)
+			use = nil == err && running
+		}
+		if use {
+			err := fn(p.snapshot(pl.node))
+			if !p.failed(ctx, pl.node, err) || !retry {
+				return p.snapshot(pl.node), err
+			}
+		}
+		p.unplace(meetingID, pl.node)
+	}
+	err := ErrNoHealthyNode
+	for _, n := range p.candidates(false) {
+		if err = fn(p.snapshot(n)); nil == err {
+			p.place(meetingID, n)
+			return p.snapshot(n), nil
+		}
+		if !p.failed(ctx, n, err) || !retry {
+			break
+		}
+	}
+	return PoolNode{}, err
+}
+
+func (p *Pool) snapshot(n *poolNode) PoolNode {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	return n.PoolNode
+}
+
+func (p *Pool) lookup(ctx context.Context, action, id string) (*poolNode, error) {
+	for refreshed := false; ; refreshed = true {
+		p.m.RLock()
+		pl, t := p.meetings[id]
+		p.m.RUnlock()
+		if t {
+			return pl.node, nil
+		}
+		if refreshed {
+			return nil, &APIError{
+				Action:     action,
+				ReturnCode: "FAILED",
+				MessageKey: "notFound",
+				Message:    fmt.Sprintf("meeting %q not found on any node", id),
+			}
+		}
+		if err := p.refreshOnce(ctx); nil != err {
+			return nil, err
+		}
+	}
+}
+
+// refreshOnce refreshes the pool unless that happened less than
+// MinLookupRefresh ago. Concurrent callers wait for a single refresh.
+func (p *Pool) refreshOnce(ctx context.Context) error {
+	p.refreshing.Lock()
+	defer p.refreshing.Unlock()
+	p.m.RLock()
+	recent := time.Since(p.refreshed) < MinLookupRefresh
+	p.m.RUnlock()
+	if recent {
+		return nil
+	}
+	return p.Refresh(ctx)
+}
+
+// CreateWithContext creates the meeting on the node hosting it or on the
+// least loaded one, failing over to the next node if one fails. Creates with
+// documents from NewDocumentReader are not retried, as the reader is
+// consumed by the first attempt.
+func (p *Pool) CreateWithContext(ctx context.Context, id string, options OptionEncoder) (*Meeting, error) {
+	var m *Meeting
+	_, err := p.assign(ctx, id, !streamsDocuments(options), func(n PoolNode) (err error) {
+		m, err = n.API.CreateWithContext(ctx, id, options)
+		return
+	})
+	if nil != err {
+		return nil, err
+	}
+	return m, nil
+}
+
+// streamsDocuments reports whether the options carry documents that can be
+// sent only once.
+func streamsDocuments(options OptionEncoder) bool {
+	if options, ok := options.(*CreateOptions); ok {
+		for _, doc := range options.Documents {
+			if nil != doc.reader {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// JoinURL returns the join URL on the node hosting the meeting, or an empty
+// string if the meeting is not known to the pool.
+func (p *Pool) JoinURL(name, meetingID, password string, options OptionEncoder) string {
+	p.m.RLock()
+	pl, t := p.meetings[meetingID]
+	p.m.RUnlock()
+	if !t {
+		return ""
+	}
+	return pl.node.API.JoinURL(name, meetingID, password, options)
+}
+
+func (p *Pool) IsMeetingRunningWithContext(ctx context.Context, id string) (bool, error) {
+	n, err := p.lookup(ctx, "isMeetingRunning", id)
+	if IsNotFound(err) {
+		return false, nil
+	} else if nil != err {
+		return false, err
+	}
+	running, err := n.API.IsMeetingRunningWithContext(ctx, id)
+	p.failed(ctx, n, err)
+	return running, err
+}
+
+func (p *Pool) EndWithContext(ctx context.Context, id, password string, options *EndOptions) error {
+	n, err := p.lookup(ctx, "end", id)
+	if nil != err {
+		return err
+	}
+	if err = n.API.EndWithContext(ctx, id, password, options); nil == err || IsNotFound(err) {
+		p.unplace(id, n)
+	}
+	p.failed(ctx, n, err)
+	return err
+}
+
+func (p *Pool) MeetingInfoWithContext(ctx context.Context, id, password string) (*Meeting, error) {
+	n, err := p.lookup(ctx, "getMeetingInfo", id)
+	if nil != err {
+		return nil, err
+	}
+	m, err := n.API.MeetingInfoWithContext(ctx, id, password)
+	p.failed(ctx, n, err)
+	return m, err
+}
+
+func (p *Pool) BreakoutRoomsWithContext(ctx context.Context, parent *Meeting) ([]*Meeting, error) {
+	n, err := p.lookup(ctx, "getMeetings", parent.Id)
+	if nil != err {
+		return nil, err
+	}
+	rooms, err := n.API.BreakoutRoomsWithContext(ctx, parent)
+	p.failed(ctx, n, err)
+	return rooms, err
+}
+
+func (p *Pool) InsertDocumentWithContext(ctx context.Context, meetingID string, docs []ConfigXML_Document) error {
+	n, err := p.lookup(ctx, "insertDocument", meetingID)
+	if nil != err {
+		return err
+	}
+	err = n.API.InsertDocumentWithContext(ctx, meetingID, docs)
+	p.failed(ctx, n, err)
+	return err
+}
+
+func (p *Pool) SetConfigXMLWithContext(ctx context.Context, meeting string, c *ConfigXML) (string, error) {
+	n, err := p.lookup(ctx, "setConfigXML", meeting)
+	if nil != err {
+		return "", err
+	}
+	token, err := n.API.SetConfigXMLWithContext(ctx, meeting, c)
+	p.failed(ctx, n, err)
+	return token, err
+}
+
+// MeetingsWithContext returns the meetings of all nodes. Nodes that fail are
+// skipped, unless all of them do.
+func (p *Pool) MeetingsWithContext(ctx context.Context) ([]*Meeting, error) {
+	var m sync.Mutex
+	meetings := []*Meeting{}
+	err := p.each(ctx, func(n *poolNode) error {
+		list, err := n.API.MeetingsWithContext(ctx)
+		if nil == err {
+			m.Lock()
+			meetings = append(meetings, list...)
+			m.Unlock()
+		}
+		return err
+	})
+	return meetings, err
+}
+
+// RecordingsWithContext returns the recordings of the meetings from all
+// nodes, ordered by start time.
+func (p *Pool) RecordingsWithContext(ctx context.Context, meetings []string) ([]*Recording, error) {
+	var m sync.Mutex
+	recordings := []*Recording{}
+	err := p.each(ctx, func(n *poolNode) error {
+		list, err := n.API.RecordingsWithContext(ctx, meetings)
+		if nil == err {
+			m.Lock()
+			recordings = append(recordings, list...)
+			m.Unlock()
+		}
+		return err
+	})
+	sortRecordings(recordings)
+	return recordings, err
+}
+
+// RecordingsPageWithContext merges the pages of all enabled nodes, ordered
+// by start time as the nodes list them. A node is asked for at most offset
+// plus limit recordings; if the request continues where the previous page
+// ended, only limit recordings from where that page ended on each node. It
+// fails if any node does, since the page would be incomplete.
+func (p *Pool) RecordingsPageWithContext(ctx context.Context, options *RecordingsOptions) (*RecordingPage, error) {
+	filter := RecordingsOptions{}
+	if nil != options {
+		filter = *options
+	}
+	offset, limit := int(filter.Offset), int(filter.Limit)
+	filter.Offset, filter.Limit = 0, 0
+	key := fmt.Sprint(filter)
+
+	nodes := p.enabled()
+	if 0 == len(nodes) {
+		return nil, ErrNoHealthyNode
+	}
+	p.m.RLock()
+	starts, resume := p.cursor.starts(key, offset, nodes)
+	p.m.RUnlock()
+	skip, count := offset, 0
+	if resume {
+		skip = 0
+	}
+	if limit > 0 {
+		count = skip + limit
+	}
+
+	lists := make([][]*Recording, len(nodes))
+	totals := make([]int, len(nodes))
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for k, n := range nodes {
+		wg.Add(1)
+		go func(k int, n *poolNode) {
+			defer wg.Done()
+			lists[k], totals[k], errs[k] = nodeRecordings(ctx, n.API, filter, starts[k], count)
+			p.failed(ctx, n, errs[k])
+		}(k, n)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if nil != err {
+			return nil, err
+		}
+	}
+
+	page := &RecordingPage{Recordings: []*Recording{}, Offset: offset}
+	ends := map[*poolNode]int{}
+	for k, n := range nodes {
+		page.Total += totals[k]
+		ends[n] = starts[k]
+	}
+	for taken := 0; limit < 1 || len(page.Recordings) < limit; taken++ {
+		next := -1
+		for k, list := range lists {
+			if len(list) > 0 && (next < 0 || recordingBefore(list[0], lists[next][0])) {
+				next = k
+			}
+		}
+		if next < 0 {
+			break
+		}
+		if taken >= skip {
+			page.Recordings = append(page.Recordings, lists[next][0])
+		}
+		lists[next] = lists[next][1:]
+		ends[nodes[next]]++
+	}
+	if limit > 0 {
+		p.m.Lock()
+		p.cursor = recordingCursor{key, offset + len(page.Recordings), ends}
+		p.m.Unlock()
+	}
+	return page, nil
+}
+
+// recordingCursor is where the last page of recordings ended on each node.
+type recordingCursor struct {
+	filter string
+	end    int
+	nodes  map[*poolNode]int
+}
+
+// starts returns the offsets on the nodes to continue from, if the page at
+// offset follows the last one.
+func (c *recordingCursor) starts(filter string, offset int, nodes []*poolNode) ([]int, bool) {
+	starts := make([]int, len(nodes))
+	if filter != c.filter || offset != c.end {
+		return starts, false
+	}
+	for k, n := range nodes {
+		start, t := c.nodes[n]
+		if !t {
+			return make([]int, len(nodes)), false
+		}
+		starts[k] = start
+	}
+	return starts, true
+}
+
+// nodeRecordings returns up to count recordings (all if count < 1) of a node
+// from start on, along with its total. Nodes that do not page return all
+// recordings at once, which are cut down here.
+func nodeRecordings(ctx context.Context, api API, filter RecordingsOptions, start, count int) ([]*Recording, int, error) {
+	list := []*Recording{}
+	for {
+		filter.Offset, filter.Limit = uint(start+len(list)), DefaultRecordingsPageSize
+		if rest := count - len(list); count > 0 && rest < DefaultRecordingsPageSize {
+			filter.Limit = uint(rest)
+		}
+		page, err := api.RecordingsPageWithContext(ctx, &filter)
+		if nil != err {
+			return nil, 0, err
+		}
+		if page.Total < 0 {
+			all := page.Recordings
+			sortRecordings(all)
+			if start > len(all) {
+				start = len(all)
+			}
+			if list = all[start:]; count > 0 && len(list) > count {
+				list = list[:count]
+			}
+			return list, len(all), nil
+		}
+		list = append(list, page.Recordings...)
+		if 0 == len(page.Recordings) || start+len(list) >= page.Total || (count > 0 && len(list) >= count) {
+			return list, page.Total, nil
+		}
+	}
+}
+
+func (p *Pool) PublishRecordingsWithContext(ctx context.Context, recordings []string, publish bool) (bool, error) {
+	action := "publishRecordings"
+	if len(recordings) < 1 {
+		return false, newMissingParamError(action, "RecordID")
+	}
+	groups, err := p.recordingNodes(ctx, action, recordings)
+	if nil != err {
+		return false, err
+	}
+	for n, ids := range groups {
+		if ok, err := n.API.PublishRecordingsWithContext(ctx, ids, publish); nil != err || !ok {
+			p.failed(ctx, n, err)
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (p *Pool) DeleteRecordingsWithContext(ctx context.Context, recordings []string) (bool, error) {
+	action := "deleteRecordings"
+	if len(recordings) < 1 {
+		return false, newMissingParamError(action, "RecordID")
+	}
+	groups, err := p.recordingNodes(ctx, action, recordings)
+	if nil != err {
+		return false, err
+	}
+	for n, ids := range groups {
+		if ok, err := n.API.DeleteRecordingsWithContext(ctx, ids); nil != err || !ok {
+			p.failed(ctx, n, err)
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (p *Pool) UpdateRecordingsWithContext(ctx context.Context, recordings []string, meta map[string]string) (map[string]error, error) {
+	action := "updateRecordings"
+	if len(recordings) < 1 {
+		return nil, newMissingParamError(action, "RecordID")
+	}
+	groups, err := p.recordingNodes(ctx, action, recordings)
+	if nil != err && !IsNotFound(err) {
+		return nil, err
+	}
+	results := map[string]error{}
+	for _, id := range recordings {
+		results[id] = &APIError{Action: action, ReturnCode: "FAILED", MessageKey: "notFound",
+			Message: "We could not find recordings"}
+	}
+	for n, ids := range groups {
+		updated, err := n.API.UpdateRecordingsWithContext(ctx, ids, meta)
+		if nil != err {
+			p.failed(ctx, n, err)
+			for _, id := range ids {
+				results[id] = err
+			}
+			continue
+		}
+		for id, err := range updated {
+			results[id] = err
+		}
+	}
+	return results, nil
+}
+
+func (p *Pool) RecordingTextTracksWithContext(ctx context.Context, recordID string) ([]TextTrack, error) {
+	groups, err := p.recordingNodes(ctx, "getRecordingTextTracks", []string{recordID})
+	if nil != err {
+		return nil, err
+	}
+	for n := range groups {
+		return n.API.RecordingTextTracksWithContext(ctx, recordID)
+	}
+	return nil, nil
+}
+
+func (p *Pool) PutRecordingTextTrackWithContext(ctx context.Context, recordID string, options *TextTrackOptions, track io.Reader) error {
+	groups, err := p.recordingNodes(ctx, "putRecordingTextTrack", []string{recordID})
+	if nil != err {
+		return err
+	}
+	for n := range groups {
+		return n.API.PutRecordingTextTrackWithContext(ctx, recordID, options, track)
+	}
+	return nil
+}
+
+// DefaultConfigXMLWithContext returns the default config.xml of the least
+// loaded node.
+func (p *Pool) DefaultConfigXMLWithContext(ctx context.Context) (*ConfigXML, error) {
+	for _, n := range p.candidates(true) {
+		c, err := n.API.DefaultConfigXMLWithContext(ctx)
+		if !p.failed(ctx, n, err) {
+			return c, err
+		}
+	}
+	return nil, ErrNoHealthyNode
+}
+
+// ServerVersionWithContext returns the API version of the least loaded
+// node.
+func (p *Pool) ServerVersionWithContext(ctx context.Context) (string, error) {
+	for _, n := range p.candidates(true) {
+		version, err := n.API.ServerVersionWithContext(ctx)
+		if !p.failed(ctx, n, err) {
+			return version, err
+		}
+	}
+	return "", ErrNoHealthyNode
+}
+
+// each calls fn for every enabled node concurrently. It returns an error
+// only if fn failed for all nodes.
+func (p *Pool) each(ctx context.Context, fn func(*poolNode) error) error {
+	nodes := p.enabled()
+	if 0 == len(nodes) {
+		return ErrNoHealthyNode
+	}
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for k, n := range nodes {
+		wg.Add(1)
+		go func(k int, n *poolNode) {
+			defer wg.Done()
+			if errs[k] = fn(n); nil != errs[k] {
+				p.failed(ctx, n, errs[k])
+			}
+		}(k, n)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if nil == err {
+			return nil
+		}
+	}
+	return errs[0]
+}
+
+// recordingNodes groups the record IDs by the node storing them, looking on
+// disabled nodes as well. IDs found on no node make it return the error of a
+// node that failed, as it may hold them, or else a notFound error, along
+// with the groups found.
+func (p *Pool) recordingNodes(ctx context.Context, action string, ids []string) (map[*poolNode][]string, error) {
+	p.m.RLock()
+	nodes := append([]*poolNode{}, p.nodes...)
+	p.m.RUnlock()
+	if 0 == len(nodes) {
+		return nil, ErrNoHealthyNode
+	}
+
+	var m sync.Mutex
+	groups, found := map[*poolNode][]string{}, map[string]bool{}
+	options := &RecordingsOptions{RecordIds: ids, States: []string{RecordingAnyState}}
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for k, n := range nodes {
+		wg.Add(1)
+		go func(k int, n *poolNode) {
+			defer wg.Done()
+			it := NewRecordingIterator(ctx, n.API, options)
+			for it.Next() {
+				m.Lock()
+				if id := it.Recording().RecordId; !found[id] {
+					found[id] = true
+					groups[n] = append(groups[n], id)
+				}
+				m.Unlock()
+			}
+			if errs[k] = it.Err(); nil != errs[k] {
+				p.failed(ctx, n, errs[k])
+			}
+		}(k, n)
+	}
+	wg.Wait()
+	for _, id := range ids {
+		if found[id] {
+			continue
+		}
+		for _, err := range errs {
+			if nil != err {
+				return groups, err
+			}
+		}
+		return groups, &APIError{
+			Action:     action,
+			ReturnCode: "FAILED",
+			MessageKey: "notFound",
+			Message:    fmt.Sprintf("recording %q not found on any node", id),
+		}
+	}
+	return groups, nil
+}
+
+func sortRecordings(recordings []*Recording) {
+	sort.SliceStable(recordings, func(i, j int) bool {
+		return recordingBefore(recordings[i], recordings[j])
+	})
+}
+
+func recordingBefore(a, b *Recording) bool {
+	return a.StartTime.Before(b.StartTime) || (a.StartTime.Equal(b.StartTime) && a.RecordId < b.RecordId)
+}
@@ -1,20 +1,180 @@
 package bbb
 
 import (
+	"context"
+	"net/url"
 	"time"
 )
 
+const (
+	RecordingProcessing  = "processing"
+	RecordingProcessed   = "processed"
+	RecordingPublished   = "published"
+	RecordingUnpublished = "unpublished"
+	RecordingDeleted     = "deleted"
+
+	// RecordingAnyState matches recordings in every state; without a state
+	// filter only published and unpublished recordings are returned.
+	RecordingAnyState = "any"
+)
+
+// DefaultRecordingsPageSize is the page size used by RecordingIterator if
+// the options have no limit; it is the maximum servers accept.
+const DefaultRecordingsPageSize = 100
+
 type Recording struct {
-	RecordId  string
-	MeetingId string
-	Name      string
-	Published bool
-	StartTime time.Time
-	EndTime   time.Time
-	Metadata  map[string]interface{}
-	Playback  struct {
-		Type string
-		Url  string
-		Len  int
+	RecordId          string
+	MeetingId         string
+	InternalMeetingId string
+	Name              string
+	Published         bool
+	State             string
+	StartTime         time.Time
+	EndTime           time.Time
+	Participants      int
+	RawSize           int64
+	Size              int64
+	Metadata          map[string]string
+	Playback          []Playback
+}
+
+// Playback describes one processed format (presentation, video, podcast,
+// notes, ...) of a recording.
+type Playback struct {
+	Type           string
+	Url            string
+	ProcessingTime time.Duration
+	Length         time.Duration
+	Size           int64
+	Preview        []PreviewImage
+}
+
+type PreviewImage struct {
+	Url    string
+	Alt    string
+	Width  int
+	Height int
+}
+
+// Format returns the playback of the given type, or nil.
+func (r *Recording) Format(typ string) *Playback {
+	for k := range r.Playback {
+		if typ == r.Playback[k].Type {
+			return &r.Playback[k]
+		}
+	}
+	return nil
+}
+
+// RecordingPage is one page of getRecordings results. Total is the number of
+// all matching recordings, or -1 if the server does not support pagination
+// (before BigBlueButton 2.6) and returned all of them.
+type RecordingPage struct {
+	Recordings []*Recording
+	Offset     int
+	Total      int
+}
+
+func (b3 *BigBlueButton) RecordingsPage(options *RecordingsOptions) (*RecordingPage, error) {
+	return b3.RecordingsPageWithContext(context.Background(), options)
+}
+
+func (b3 *BigBlueButton) RecordingsPageWithContext(ctx context.Context, options *RecordingsOptions) (*RecordingPage, error) {
+	q := url.Values{}
+	if nil != options {
+		q = options.Values()
+	}
+	u := b3.makeURL("getRecordings", q)
+	res, err := b3.get(ctx, u.String())
+	if nil != err {
+		return nil, err
+	}
+	defer closeResponse(res)
+	var response recordingsResponse
+	if err := loadResponse(res, "getRecordings", &response); nil != err {
+		return nil, err
 	}
+	page := &RecordingPage{Recordings: response.recordings(), Total: -1}
+	if nil != options {
+		page.Offset = int(options.Offset)
+	}
+	if nil != response.Total {
+		page.Total = int(*response.Total)
+	}
+	return page, nil
+}
+
+// RecordingIterator pages through the recordings matching its options:
+//
+//	it := b3.IterateRecordings(ctx, &bbb.RecordingsOptions{States: []string{bbb.RecordingPublished}})
+//	for it.Next() {
+//		r := it.Recording()
+//		...
+//	}
+//	if err := it.Err(); nil != err {
+//		...
+//	}
+type RecordingIterator struct {
+	api     API
+	ctx     context.Context
+	options RecordingsOptions
+	page    []*Recording
+	current *Recording
+	total   int
+	done    bool
+	err     error
+}
+
+// IterateRecordings returns an iterator starting at options.Offset, which
+// fetches options.Limit (or DefaultRecordingsPageSize) recordings at once.
+func (b3 *BigBlueButton) IterateRecordings(ctx context.Context, options *RecordingsOptions) *RecordingIterator {
+	return NewRecordingIterator(ctx, b3, options)
+}
+
+// NewRecordingIterator is like BigBlueButton.IterateRecordings for any API.
+func NewRecordingIterator(ctx context.Context, api API, options *RecordingsOptions) *RecordingIterator {
+	it := &RecordingIterator{api: api, ctx: ctx, total: -1}
+	if nil != options {
+		it.options = *options
+	}
+	if 0 == it.options.Limit {
+		it.options.Limit = DefaultRecordingsPageSize
+	}
+	return it
+}
+
+// Next advances to the next recording, fetching the next page if needed. It
+// returns false when there are no more recordings or an error occurred.
+func (it *RecordingIterator) Next() bool {
+	for 0 == len(it.page) {
+		if it.done || nil != it.err {
+			it.current = nil
+			return false
+		}
+		page, err := it.api.RecordingsPageWithContext(it.ctx, &it.options)
+		if nil != err {
+			it.err = err
+			continue
+		}
+		it.page, it.total = page.Recordings, page.Total
+		it.options.Offset += uint(len(page.Recordings))
+		it.done = page.Total < 0 || 0 == len(page.Recordings) || int(it.options.Offset) >= page.Total
+	}
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Recording returns the current recording.
+func (it *RecordingIterator) Recording() *Recording {
+	return it.current
+}
+
+// Total returns the number of matching recordings reported by the server,
+// or -1 if unknown.
+func (it *RecordingIterator) Total() int {
+	return it.total
+}
+
+func (it *RecordingIterator) Err() error {
+	return it.err
 }
@@ -0,0 +1,145 @@
+package bbb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// TextTrack is a caption or subtitle track of a recording.
+type TextTrack struct {
+	Href   string `json:"href"`
+	Kind   string `json:"kind"`
+	Label  string `json:"label"`
+	Lang   string `json:"lang"`
+	Source string `json:"source"`
+}
+
+type TextTrackOptions struct {
+	Kind  string `json:"kind"`  // "subtitles" (default) or "captions"
+	Lang  string `json:"lang"`  // locale, e.g. "en-US"
+	Label string `json:"label"` // human readable name shown in the player
+
+	// Filename of the uploaded track, its extension selects the format
+	// (".vtt" or ".srt").
+	Filename string `json:"filename"`
+}
+
+func (opt *TextTrackOptions) Values() url.Values {
+	values := reflectOptionValues(reflect.ValueOf(*opt), true,
+		func(k string, _ reflect.Value) bool {
+			return "filename" != k
+		})
+	if "" == values.Get("kind") {
+		values.Set("kind", "subtitles")
+	}
+	return values
+}
+
+func (opt *TextTrackOptions) contentType() string {
+	if ".srt" == strings.ToLower(filepath.Ext(opt.Filename)) {
+		return "application/x-subrip"
+	}
+	return "text/vtt"
+}
+
+func (b3 *BigBlueButton) RecordingTextTracks(recordID string) ([]TextTrack, error) {
+	return b3.RecordingTextTracksWithContext(context.Background(), recordID)
+}
+
+func (b3 *BigBlueButton) RecordingTextTracksWithContext(ctx context.Context, recordID string) ([]TextTrack, error) {
+	action := "getRecordingTextTracks"
+	u := b3.makeURL(action, url.Values{"recordID": {recordID}})
+	res, err := b3.get(ctx, u.String())
+	if nil != err {
+		return nil, err
+	}
+	defer closeResponse(res)
+	response, err := loadJSONResponse(res, action)
+	if nil != err {
+		return nil, err
+	}
+	return response.Tracks, nil
+}
+
+// PutRecordingTextTrack uploads a caption track read from track. The body
+// is streamed as multipart/form-data and never buffered in memory.
+func (b3 *BigBlueButton) PutRecordingTextTrack(recordID string, options *TextTrackOptions, track io.Reader) error {
+	return b3.PutRecordingTextTrackWithContext(context.Background(), recordID, options, track)
+}
+
+func (b3 *BigBlueButton) PutRecordingTextTrackWithContext(ctx context.Context, recordID string, options *TextTrackOptions, track io.Reader) error {
+	action := "putRecordingTextTrack"
+	if nil == options {
+		options = &TextTrackOptions{}
+	}
+	u := b3.makeURL(action, mergeUrlValues(url.Values{"recordID": {recordID}}, options.Values()))
+
+	body, w := io.Pipe()
+	form := multipart.NewWriter(w)
+	go func() {
+		filename := options.Filename
+		if "" == filename {
+			filename = "track.vtt"
+		}
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", `form-data; name="file"; filename="`+
+			strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(filename)+`"`)
+		h.Set("Content-Type", options.contentType())
+		part, err := form.CreatePart(h)
+		if nil == err {
+			_, err = io.Copy(part, track)
+		}
+		if nil == err {
+			err = form.Close()
+		}
+		w.CloseWithError(err)
+	}()
+
+	res, err := b3.post(ctx, u.String(), form.FormDataContentType(), body)
+	body.CloseWithError(io.ErrClosedPipe)
+	if nil != err {
+		return err
+	}
+	defer closeResponse(res)
+	_, err = loadJSONResponse(res, action)
+	return err
+}
+
+type jsonResponse struct {
+	ReturnCode string      `json:"returncode"`
+	MessageKey string      `json:"messageKey"`
+	Message    string      `json:"message"`
+	Tracks     []TextTrack `json:"tracks"`
+}
+
+// loadJSONResponse decodes the JSON {"response": {...}} envelope used by the
+// text track actions.
+func loadJSONResponse(r *http.Response, action string) (*jsonResponse, error) {
+	var envelope struct {
+		Response *jsonResponse `json:"response"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&envelope); nil != err || nil == envelope.Response {
+		if r.StatusCode >= 300 {
+			return nil, newHTTPError(action, r.StatusCode)
+		}
+		return nil, &APIError{Action: action, Message: "invalid response", StatusCode: r.StatusCode}
+	}
+	if response := envelope.Response; "SUCCESS" != response.ReturnCode || r.StatusCode >= 300 {
+		return nil, &APIError{
+			Action:     action,
+			ReturnCode: response.ReturnCode,
+			MessageKey: response.MessageKey,
+			Message:    response.Message,
+			StatusCode: r.StatusCode,
+		}
+	}
+	return envelope.Response, nil
+}
@@ -0,0 +1,333 @@
+package bbb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const DefaultWatchInterval = 10 * time.Second
+
+type WatchEventType int
+
+const (
+	MeetingStarted WatchEventType = iota
+	MeetingEnded
+	UserJoined
+	UserLeft
+	RoleChanged
+	PresenterChanged
+	RecordingStarted
+	WatchFailed
+)
+
+func (t WatchEventType) String() string {
+	switch t {
+	case MeetingStarted:
+		return "meeting-started"
+	case MeetingEnded:
+		return "meeting-ended"
+	case UserJoined:
+		return "user-joined"
+	case UserLeft:
+		return "user-left"
+	case RoleChanged:
+		return "role-changed"
+	case PresenterChanged:
+		return "presenter-changed"
+	case RecordingStarted:
+		return "recording-started"
+	case WatchFailed:
+		return "watch-failed"
+	}
+	return fmt.Sprintf("WatchEventType(%d)", int(t))
+}
+
+// WatchEvent is a change found between two polls. Meeting is the latest
+// snapshot, or the last one seen if the meeting has gone away. Attendee is
+// set for user events; for RoleChanged Previous holds the attendee as it
+// was, for PresenterChanged the previous presenter (either may be nil).
+// Presenter changes are reported only while the meeting keeps running.
+// WatchFailed events carry the polling error in Err.
+type WatchEvent struct {
+	Type     WatchEventType
+	Time     time.Time
+	Meeting  *Meeting
+	Attendee *Attendee
+	Previous *Attendee
+	Err      error
+}
+
+// Watcher polls a server and reports the differences between consecutive
+// snapshots as events. By default all meetings of the server are watched
+// with getMeetings; once meetings are added with Watch, only those are
+// polled, with getMeetingInfo.
+//
+// The first successful poll, and the first one after switching between the
+// two modes, only records the current state; so does the first one for a
+// meeting added with Watch.
+type Watcher struct {
+	// Interval between polls; DefaultWatchInterval if 0.
+	Interval time.Duration
+
+	api     API
+	events  chan WatchEvent
+	last    map[string]*Meeting
+	m       sync.Mutex
+	watched map[string]string
+	pending map[string]bool
+	fresh   bool
+	mode    int
+}
+
+func NewWatcher(api API, interval time.Duration) *Watcher {
+	return &Watcher{
+		Interval: interval,
+		api:      api,
+		events:   make(chan WatchEvent, 64),
+		watched:  map[string]string{},
+		pending:  map[string]bool{},
+		fresh:    true,
+	}
+}
+
+// Watch restricts the watcher to the given meeting, in addition to those
+// already watched. The password is passed to getMeetingInfo and may be
+// empty on recent servers.
+func (w *Watcher) Watch(id, password string) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if 0 == len(w.watched) {
+		w.fresh = true
+		w.mode++
+	} else if _, t := w.watched[id]; !t {
+		w.pending[id] = true
+	}
+	w.watched[id] = password
+}
+
+// Unwatch stops watching the meeting; if it was the last one, the watcher
+// goes back to watching the whole server.
+func (w *Watcher) Unwatch(id string) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if _, t := w.watched[id]; t {
+		delete(w.watched, id)
+		delete(w.pending, id)
+		if 0 == len(w.watched) {
+			w.fresh = true
+			w.mode++
+		}
+	}
+}
+
+// Events returns the channel events are sent on. It is closed when Run
+// returns.
+func (w *Watcher) Events() <-chan WatchEvent {
+	return w.events
+}
+
+// Run polls until ctx is done and returns its error. Events are sent on
+// the Events channel, which must be drained. Run may be called only once.
+func (w *Watcher) Run(ctx context.Context) error {
+	defer close(w.events)
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		events := w.poll(ctx)
+		if err := ctx.Err(); nil != err {
+			return err
+		}
+		for _, ev := range events {
+			select {
+			case w.events <- ev:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (w *Watcher) poll(ctx context.Context) []WatchEvent {
+	w.m.Lock()
+	watched, pending := make(map[string]string, len(w.watched)), map[string]bool{}
+	for id, password := range w.watched {
+		watched[id] = password
+	}
+	for id := range w.pending {
+		pending[id] = true
+	}
+	fresh, mode := w.fresh, w.mode
+	w.m.Unlock()
+
+	now, current, events := time.Now(), map[string]*Meeting{}, []WatchEvent{}
+	failed := map[string]bool{}
+	if 0 == len(watched) {
+		meetings, err := w.api.MeetingsWithContext(ctx)
+		if nil != err {
+			return []WatchEvent{{Type: WatchFailed, Time: now, Err: err}}
+		}
+		for _, m := range meetings {
+			current[m.Id] = m
+		}
+	} else {
+		for id, password := range watched {
+			m, err := w.api.MeetingInfoWithContext(ctx, id, password)
+			switch {
+			case nil == err:
+				current[id] = m
+			case IsNotFound(err):
+			default:
+				failed[id] = true
+				events = append(events, WatchEvent{Type: WatchFailed, Time: now, Meeting: w.last[id], Err: err})
+				if m, t := w.last[id]; t {
+					current[id] = m
+				}
+			}
+		}
+		for id := range w.last {
+			if _, t := watched[id]; !t {
+				delete(w.last, id)
+			}
+		}
+	}
+	if !fresh {
+		prev, cur := map[string]*Meeting{}, map[string]*Meeting{}
+		for id, m := range w.last {
+			if !pending[id] {
+				prev[id] = m
+			}
+		}
+		for id, m := range current {
+			if !pending[id] {
+				cur[id] = m
+			}
+		}
+		events = append(events, diffMeetings(prev, cur, now)...)
+	}
+	w.last = current
+
+	// Meetings that could not be polled still need a baseline.
+	w.m.Lock()
+	defer w.m.Unlock()
+	if mode == w.mode {
+		for id := range pending {
+			if !failed[id] {
+				delete(w.pending, id)
+			}
+		}
+		if fresh {
+			w.fresh = false
+			for id := range failed {
+				if _, t := w.watched[id]; t {
+					w.pending[id] = true
+				}
+			}
+		}
+	}
+	return events
+}
+
+// diffMeetings returns the events that lead from prev to cur.
+func diffMeetings(prev, cur map[string]*Meeting, now time.Time) []WatchEvent {
+	ids := []string{}
+	for id := range prev {
+		ids = append(ids, id)
+	}
+	for id := range cur {
+		if _, t := prev[id]; !t {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	events := []WatchEvent{}
+	for _, id := range ids {
+		before, after := prev[id], cur[id]
+		meeting := after
+		if nil == meeting {
+			meeting = before
+		}
+		add := func(typ WatchEventType, a, previous *Attendee) {
+			events = append(events, WatchEvent{Type: typ, Time: now, Meeting: meeting, Attendee: a, Previous: previous})
+		}
+		running, wasRunning := nil != after && after.Running, nil != before && before.Running
+		if running && !wasRunning {
+			add(MeetingStarted, nil, nil)
+		}
+		if nil != after && after.Recording && (nil == before || !before.Recording) {
+			add(RecordingStarted, nil, nil)
+		}
+
+		was, is := attendeesByKey(before), attendeesByKey(after)
+		for _, a := range attendees(before) {
+			if _, t := is[a.key()]; !t {
+				add(UserLeft, a, nil)
+			}
+		}
+		for _, a := range attendees(after) {
+			if p, t := was[a.key()]; !t {
+				add(UserJoined, a, nil)
+			} else if p.Role != a.Role {
+				add(RoleChanged, a, p)
+			}
+		}
+		if running && wasRunning {
+			p, a := presenter(before), presenter(after)
+			if (nil == p) != (nil == a) || (nil != p && p.key() != a.key()) {
+				add(PresenterChanged, a, p)
+			}
+		}
+
+		if wasRunning && !running {
+			add(MeetingEnded, nil, nil)
+		}
+	}
+	return events
+}
+
+func (a *Attendee) key() string {
+	if "" != a.InternalUserId {
+		return a.InternalUserId
+	}
+	return a.UserId
+}
+
+func attendees(m *Meeting) []*Attendee {
+	if nil == m {
+		return nil
+	}
+	list := make([]*Attendee, len(m.Attendees))
+	for k := range m.Attendees {
+		list[k] = &m.Attendees[k]
+	}
+	return list
+}
+
+func attendeesByKey(m *Meeting) map[string]*Attendee {
+	index := map[string]*Attendee{}
+	for _, a := range attendees(m) {
+		index[a.key()] = a
+	}
+	return index
+}
+
+func presenter(m *Meeting) *Attendee {
+	for _, a := range attendees(m) {
+		if a.IsPresenter {
+			return a
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/sdgoij/gobbb"
+)
+
+// Event is one of the typed events below; use a type switch to tell them
+// apart. Events this package does not know are delivered as *Unknown.
+type Event interface {
+	Header() *EventHeader
+}
+
+type EventHeader struct {
+	Id                string
+	Timestamp         time.Time
+	MeetingId         string
+	InternalMeetingId string
+}
+
+func (h *EventHeader) Header() *EventHeader { return h }
+
+type MeetingCreated struct {
+	EventHeader
+	Meeting *bbb.Meeting
+}
+
+type MeetingEnded struct {
+	EventHeader
+}
+
+// UserJoined and UserLeft carry the attendee as it would be reported by
+// getMeetingInfo.
+type UserJoined struct {
+	EventHeader
+	Attendee bbb.Attendee
+}
+
+type UserLeft struct {
+	EventHeader
+	Attendee bbb.Attendee
+}
+
+// UserPresenter is sent for "user-presenter-assigned" and
+// "user-presenter-unassigned".
+type UserPresenter struct {
+	EventHeader
+	UserId    string
+	Presenter bool
+}
+
+// MeetingRecording is sent for "meeting-recording-started",
+// "meeting-recording-stopped" and "meeting-recording-unhandled".
+type MeetingRecording struct {
+	EventHeader
+	Recording bool
+}
+
+// RecordingStep is sent for the record and playback ("rap-*") events of the
+// recording pipeline, e.g. "rap-archive-ended" or "rap-publish-ended".
+type RecordingStep struct {
+	EventHeader
+	RecordId  string
+	Success   bool
+	StepTime  time.Duration
+	Recording *bbb.Recording
+}
+
+type Unknown struct {
+	EventHeader
+	Attributes json.RawMessage
+}
+
+type message struct {
+	Data struct {
+		Type       string          `json:"type"`
+		Id         string          `json:"id"`
+		Attributes json.RawMessage `json:"attributes"`
+		Event      struct {
+			Ts json.Number `json:"ts"`
+		} `json:"event"`
+	} `json:"data"`
+}
+
+type meetingAttributes struct {
+	InternalMeetingId string            `json:"internal-meeting-id"`
+	ExternalMeetingId string            `json:"external-meeting-id"`
+	Name              string            `json:"name"`
+	IsBreakout        bool              `json:"is-breakout"`
+	CreateTime        json.Number       `json:"create-time"`
+	ModeratorPass     string            `json:"moderator-pass"`
+	ViewerPass        string            `json:"viewer-pass"`
+	Record            bool              `json:"record"`
+	VoiceConf         json.Number       `json:"voice-conf"`
+	MaxUsers          json.Number       `json:"max-users"`
+	Metadata          map[string]string `json:"metadata"`
+}
+
+type userAttributes struct {
+	InternalUserId string `json:"internal-user-id"`
+	ExternalUserId string `json:"external-user-id"`
+	Name           string `json:"name"`
+	Role           string `json:"role"`
+	Presenter      bool   `json:"presenter"`
+}
+
+type recordingAttributes struct {
+	Name      string            `json:"name"`
+	StartTime json.Number       `json:"startTime"`
+	EndTime   json.Number       `json:"endTime"`
+	Size      json.Number       `json:"size"`
+	RawSize   json.Number       `json:"rawSize"`
+	Metadata  map[string]string `json:"metadata"`
+	Playback  *struct {
+		Format         string      `json:"format"`
+		Link           string      `json:"link"`
+		ProcessingTime json.Number `json:"processingTime"`
+		Duration       json.Number `json:"duration"`
+	} `json:"playback"`
+}
+
+type attributes struct {
+	Meeting   meetingAttributes    `json:"meeting"`
+	User      *userAttributes      `json:"user"`
+	RecordId  string               `json:"record-id"`
+	Success   bool                 `json:"success"`
+	StepTime  json.Number          `json:"step-time"`
+	Recording *recordingAttributes `json:"recording"`
+}
+
+func decodeEvent(data json.RawMessage) (Event, error) {
+	var m message
+	if err := json.Unmarshal(data, &m); nil != err {
+		return nil, err
+	}
+	var attr attributes
+	if len(m.Data.Attributes) > 0 {
+		if err := json.Unmarshal(m.Data.Attributes, &attr); nil != err {
+			return nil, err
+		}
+	}
+	h := EventHeader{
+		Id:                m.Data.Id,
+		Timestamp:         mstime(m.Data.Event.Ts),
+		MeetingId:         attr.Meeting.ExternalMeetingId,
+		InternalMeetingId: attr.Meeting.InternalMeetingId,
+	}
+	switch id := m.Data.Id; {
+	case "meeting-created" == id:
+		return &MeetingCreated{h, attr.Meeting.meeting()}, nil
+	case "meeting-ended" == id:
+		return &MeetingEnded{h}, nil
+	case "user-joined" == id && nil != attr.User:
+		return &UserJoined{h, attr.User.attendee()}, nil
+	case "user-left" == id && nil != attr.User:
+		return &UserLeft{h, attr.User.attendee()}, nil
+	case strings.HasPrefix(id, "user-presenter-") && nil != attr.User:
+		return &UserPresenter{h, attr.User.ExternalUserId, "user-presenter-assigned" == id}, nil
+	case strings.HasPrefix(id, "meeting-recording-"):
+		return &MeetingRecording{h, "meeting-recording-started" == id}, nil
+	case strings.HasPrefix(id, "rap-"):
+		ev := &RecordingStep{
+			EventHeader: h,
+			RecordId:    attr.RecordId,
+			Success:     attr.Success,
+			StepTime:    time.Duration(parseInt(attr.StepTime)) * time.Millisecond,
+		}
+		if nil != attr.Recording {
+			ev.Recording = attr.Recording.recording(attr)
+		}
+		return ev, nil
+	}
+	return &Unknown{h, m.Data.Attributes}, nil
+}
+
+func (m meetingAttributes) meeting() *bbb.Meeting {
+	return &bbb.Meeting{
+		Id:          m.ExternalMeetingId,
+		InternalId:  m.InternalMeetingId,
+		Name:        m.Name,
+		CreateTime:  mstime(m.CreateTime),
+		VoiceBridge: int(parseInt(m.VoiceConf)),
+		AttendeePW:  m.ViewerPass,
+		ModeratorPW: m.ModeratorPass,
+		Running:     true,
+		MaxUsers:    int(parseInt(m.MaxUsers)),
+		Metadata:    m.Metadata,
+		IsBreakout:  m.IsBreakout,
+	}
+}
+
+func (u userAttributes) attendee() bbb.Attendee {
+	return bbb.Attendee{
+		UserId:         u.ExternalUserId,
+		InternalUserId: u.InternalUserId,
+		Name:           u.Name,
+		Role:           u.Role,
+		IsPresenter:    u.Presenter,
+	}
+}
+
+func (r recordingAttributes) recording(attr attributes) *bbb.Recording {
+	recording := &bbb.Recording{
+		RecordId:          attr.RecordId,
+		MeetingId:         attr.Meeting.ExternalMeetingId,
+		InternalMeetingId: attr.Meeting.InternalMeetingId,
+		Name:              r.Name,
+		StartTime:         mstime(r.StartTime),
+		EndTime:           mstime(r.EndTime),
+		RawSize:           parseInt(r.RawSize),
+		Size:              parseInt(r.Size),
+		Metadata:          r.Metadata,
+	}
+	if nil != r.Playback {
+		recording.Playback = []bbb.Playback{{
+			Type:           r.Playback.Format,
+			Url:            r.Playback.Link,
+			ProcessingTime: time.Duration(parseInt(r.Playback.ProcessingTime)) * time.Millisecond,
+			Length:         time.Duration(parseInt(r.Playback.Duration)) * time.Millisecond,
+		}}
+	}
+	return recording
+}
+
+func mstime(ms json.Number) time.Time {
+	if "" == ms || "0" == ms {
+		return time.Time{}
+	}
+	return time.Unix(0, parseInt(ms)*int64(time.Millisecond))
+}
@@ -0,0 +1,185 @@
+// Package webhook receives the callbacks of bbb-webhooks subscriptions (see
+// bbb.Hooks) and decodes them into typed events.
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sdgoij/gobbb"
+)
+
+type HandlerFunc func(Event)
+
+// Handler is an http.Handler for bbb-webhooks callbacks. Every callback is
+// authenticated with Secret, the shared secret of the BigBlueButton server,
+// either by its checksum or by a "Bearer" Authorization header.
+type Handler struct {
+	Secret string
+
+	// CallbackURL is the URL the hook was registered with. It is part of the
+	// checksum; if empty, it is reconstructed from the incoming request, which
+	// does not work behind proxies that rewrite the URL.
+	CallbackURL string
+
+	handlers map[string][]HandlerFunc
+	m        sync.RWMutex
+}
+
+func New(secret string) *Handler {
+	return &Handler{Secret: secret}
+}
+
+// Handle registers fn for all events with the given id (e.g. "user-joined"),
+// or for every event if id is empty.
+func (h *Handler) Handle(id string, fn HandlerFunc) {
+	h.m.Lock()
+	defer h.m.Unlock()
+	if nil == h.handlers {
+		h.handlers = map[string][]HandlerFunc{}
+	}
+	h.handlers[id] = append(h.handlers[id], fn)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if "POST" != req.Method {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := req.ParseForm(); nil != err {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.verify(req) {
+		http.Error(w, "Checksum error", http.StatusUnauthorized)
+		return
+	}
+	events, err := Decode(req.PostForm.Get("event"))
+	if nil != err {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, ev := range events {
+		h.dispatch(ev)
+	}
+}
+
+func (h *Handler) dispatch(ev Event) {
+	h.m.RLock()
+	handlers := append(append([]HandlerFunc{}, h.handlers[ev.Header().Id]...), h.handlers[""]...)
+	h.m.RUnlock()
+	for _, fn := range handlers {
+		fn(ev)
+	}
+}
+
+func (h *Handler) verify(req *http.Request) bool {
+	if auth := req.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return 1 == subtle.ConstantTimeCompare([]byte(auth[7:]), []byte(h.Secret))
+	}
+	checksum := strings.ToLower(req.URL.Query().Get("checksum"))
+	a, ok := bbb.ChecksumAlgorithmOf(checksum)
+	if !ok {
+		return false
+	}
+	expected := a.Sum(h.callbackURL(req), payload(req), h.Secret)
+	return 1 == subtle.ConstantTimeCompare([]byte(expected), []byte(checksum))
+}
+
+func (h *Handler) callbackURL(req *http.Request) string {
+	if "" != h.CallbackURL {
+		return h.CallbackURL
+	}
+	scheme := "http"
+	if nil != req.TLS {
+		scheme = "https"
+	}
+	if proto := req.Header.Get("X-Forwarded-Proto"); "" != proto {
+		scheme = proto
+	}
+	u := scheme + "://" + req.Host + req.URL.EscapedPath()
+	// bbb-webhooks appends "checksum=..." to the registered URL.
+	if q := req.URL.RawQuery; "" != q {
+		if i := strings.LastIndex(q, "checksum="); 0 == i {
+			q = ""
+		} else if i > 0 {
+			q = q[:i-1]
+		}
+		if "" != q {
+			u += "?" + q
+		}
+	}
+	return u
+}
+
+// payload rebuilds the JSON.stringify()ed form data the checksum of
+// bbb-webhooks is computed over.
+func payload(req *http.Request) string {
+	form := req.PostForm
+	s := `{"event":` + stringify(form.Get("event")) + `,"timestamp":` + form.Get("timestamp")
+	if _, t := form["domain"]; t {
+		s += `,"domain":` + stringify(form.Get("domain"))
+	}
+	return s + "}"
+}
+
+// stringify quotes s exactly like JavaScript's JSON.stringify.
+func stringify(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				b.WriteString(`\u` + fmt.Sprintf("%04x", r))
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Decode parses the "event" form value of a callback, a JSON array of
+// messages.
+func Decode(data string) ([]Event, error) {
+	var messages []json.RawMessage
+	if err := json.Unmarshal([]byte(data), &messages); nil != err {
+		return nil, err
+	}
+	events := make([]Event, 0, len(messages))
+	for _, message := range messages {
+		ev, err := decodeEvent(message)
+		if nil != err {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+func parseInt(v json.Number) int64 {
+	i, _ := strconv.ParseInt(string(v), 10, 64)
+	return i
+}
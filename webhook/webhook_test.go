@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const userJoined = `[{"data":{"type":"event","id":"user-joined","attributes":{` +
+	`"meeting":{"internal-meeting-id":"i-1","external-meeting-id":"m-1"},` +
+	`"user":{"internal-user-id":"w_1","external-user-id":"u-1","name":"Tim <T>","role":"MODERATOR","presenter":true}},` +
+	`"event":{"ts":1532718316938}}}]`
+
+func callback(checksum string) *http.Request {
+	form := url.Values{
+		"event":     {userJoined},
+		"timestamp": {"1532718316953"},
+		"domain":    {"bbb.example.com"},
+	}
+	req := httptest.NewRequest("POST", "http://example.com/hook?x=1&checksum="+checksum,
+		strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestHandler(t *testing.T) {
+	h := New("secret")
+	var events []Event
+	h.Handle("user-joined", func(ev Event) { events = append(events, ev) })
+	h.Handle("", func(ev Event) { events = append(events, ev) })
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, callback("d7e87e2e5df5b38333f12c5bbb80e7ab40dd9c82"))
+	if http.StatusOK != w.Code || 2 != len(events) {
+		t.Fatalf("unexpected response %d, events: %v", w.Code, events)
+	}
+	ev, ok := events[0].(*UserJoined)
+	if !ok {
+		t.Fatalf("expected *UserJoined, got %T", events[0])
+	}
+	if "m-1" != ev.MeetingId || "i-1" != ev.InternalMeetingId || "u-1" != ev.Attendee.UserId ||
+		"Tim <T>" != ev.Attendee.Name || "MODERATOR" != ev.Attendee.Role ||
+		1532718316938 != ev.Timestamp.UnixNano()/1e6 {
+		t.Errorf("unexpected event: %#v", ev)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, callback("0000000000000000000000000000000000000000"))
+	if http.StatusUnauthorized != w.Code || 2 != len(events) {
+		t.Errorf("expected checksum error, got %d", w.Code)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	events, err := Decode(`[{"data":{"type":"event","id":"rap-publish-ended","attributes":{` +
+		`"meeting":{"internal-meeting-id":"i-1","external-meeting-id":"m-1"},` +
+		`"record-id":"i-1","success":true,"step-time":1500,` +
+		`"recording":{"name":"Lecture","startTime":1532718316938,"size":42,` +
+		`"metadata":{"course":"cs101"},"playback":{"format":"presentation","link":"http://example.com/p"}}},` +
+		`"event":{"ts":1532718316938}}},` +
+		`{"data":{"type":"event","id":"chat-group-message-sent","attributes":{},"event":{"ts":1}}}]`)
+	if nil != err || 2 != len(events) {
+		t.Fatalf("unexpected events: %v (%v)", events, err)
+	}
+	ev, ok := events[0].(*RecordingStep)
+	if !ok || !ev.Success || "i-1" != ev.RecordId || nil == ev.Recording ||
+		"cs101" != ev.Recording.Metadata["course"] || "presentation" != ev.Recording.Playback[0].Type {
+		t.Errorf("unexpected event: %#v", events[0])
+	} else if r := ev.Recording; 1532718316938 != r.StartTime.UnixNano()/int64(time.Millisecond) || !r.EndTime.IsZero() {
+		t.Errorf("unexpected recording times: %v, %v", r.StartTime, r.EndTime)
+	}
+	if _, ok := events[1].(*Unknown); !ok || "chat-group-message-sent" != events[1].Header().Id {
+		t.Errorf("expected *Unknown, got %#v", events[1])
+	}
+}
@@ -1,154 +1,558 @@
 package bbb
 
 import (
+	"encoding/xml"
+	"fmt"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
-
-	"github.com/sdgoij/go-pkg-xmlx"
 )
 
-func loadResponseXML(r *http.Response) (response *xmlx.Node, err error) {
-	var doc *xmlx.Document = xmlx.New()
-	if err = doc.LoadStream(r.Body, nil); nil != err {
-		return
+// response is implemented by all response types, which embed responseXML.
+type response interface {
+	status() *responseXML
+}
+
+// responseXML is the envelope of every API response, the <response> root
+// element.
+type responseXML struct {
+	ReturnCode string `xml:"returncode"`
+	MessageKey string `xml:"messageKey"`
+	Message    string `xml:"message"`
+}
+
+func (r *responseXML) status() *responseXML { return r }
+
+// CheckResponse reads a response to the action and returns the error it
+// reports, if any, like the client methods do. It lets proxies pass server
+// responses on unchanged.
+func CheckResponse(r *http.Response, action string) error {
+	return loadResponse(r, action, &responseXML{})
+}
+
+func loadResponse(r *http.Response, action string, v response) error {
+	if err := decodeResponse(r.Body, v); nil != err {
+		if r.StatusCode >= 300 {
+			return newHTTPError(action, r.StatusCode)
+		}
+		return &APIError{Action: action, Message: "invalid response", StatusCode: r.StatusCode}
+	}
+	if status := v.status(); "SUCCESS" != status.ReturnCode || r.StatusCode >= 300 {
+		return &APIError{
+			Action:     action,
+			ReturnCode: status.ReturnCode,
+			MessageKey: status.MessageKey,
+			Message:    status.Message,
+			StatusCode: r.StatusCode,
+		}
 	}
-	response = doc.SelectNode("", "response")
-	if code := response.S("", "returncode"); code != "SUCCESS" {
-		err = xmlError(code + " " + response.S("", "messageKey"))
+	return nil
+}
+
+func decodeResponse(r io.Reader, v response) error {
+	d := xml.NewDecoder(r)
+	for {
+		token, err := d.Token()
+		if nil != err {
+			return err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			if "response" != start.Name.Local {
+				return fmt.Errorf("unexpected element <%s>", start.Name.Local)
+			}
+			return d.DecodeElement(v, &start)
+		}
 	}
-	return
+}
+
+type meetingResponse struct {
+	responseXML
+	meetingXML
+}
+
+type meetingsResponse struct {
+	responseXML
+	Meetings []meetingXML `xml:"meetings>meeting"`
+}
+
+type recordingsResponse struct {
+	responseXML
+	Recordings []recordingXML `xml:"recordings>recording"`
+
+	// Total is only sent by servers that support pagination.
+	Total *xmlInt `xml:"totalElements"`
+}
+
+type runningResponse struct {
+	responseXML
+	Running xmlBool `xml:"running"`
+}
+
+type publishResponse struct {
+	responseXML
+	Published xmlBool `xml:"published"`
+}
+
+type deleteResponse struct {
+	responseXML
+	Deleted xmlBool `xml:"deleted"`
+}
+
+type updateResponse struct {
+	responseXML
+	Updated xmlBool `xml:"updated"`
+}
+
+type configTokenResponse struct {
+	responseXML
+	ConfigToken string `xml:"configToken"`
+}
+
+type versionResponse struct {
+	responseXML
+	Version string `xml:"version"`
+}
+
+type hookResponse struct {
+	responseXML
+	hookXML
+}
+
+type hooksResponse struct {
+	responseXML
+	Hooks []hookXML `xml:"hooks>hook"`
+}
+
+type hookDestroyResponse struct {
+	responseXML
+	Removed xmlBool `xml:"removed"`
 }
 
 func loadMeetingCreateResponse(r *http.Response) (*Meeting, error) {
-	if response, err := loadResponseXML(r); nil == err {
-		return xml2meeting(response), nil
-	} else {
+	var response meetingResponse
+	if err := loadResponse(r, "create", &response); nil != err {
 		return nil, err
 	}
+	return response.meeting(), nil
 }
 
 func loadMeetingInfoResponse(r *http.Response) (*Meeting, error) {
-	if response, err := loadResponseXML(r); nil == err {
-		nodes := response.SelectNodes("", "attendee")
-		attendees := make([]Attendee, len(nodes))
-		for k, v := range nodes {
-			attendees[k] = Attendee{
-				UserId: v.S("", "userID"),
-				Name:   v.S("", "fullName"),
-				Role:   v.S("", "role"),
-			}
-		}
-		return &Meeting{
-			Id:          response.S("", "meetingID"),
-			Name:        response.S("", "meetingName"),
-			CreateTime:  mstime(response.I64("", "createTime")),
-			VoiceBridge: response.I("", "voiceBridge"),
-			AttendeePW:  response.S("", "attendeePW"),
-			ModeratorPW: response.S("", "moderatorPW"),
-			Running:     response.B("", "running"),
-			Recording:   response.B("", "recording"),
-			ForcedEnd:   response.B("", "hasBeenForciblyEnded"),
-			StartTime:   mstime(response.I64("", "startTime")),
-			EndTime:     mstime(response.I64("", "endTime")),
-			NumUsers:    response.I("", "participantCount"),
-			NumMod:      response.I("", "moderatorCount"),
-			MaxUsers:    response.I("", "maxUsers"),
-			Attendees:   attendees,
-		}, nil
-	} else {
+	var response meetingResponse
+	if err := loadResponse(r, "getMeetingInfo", &response); nil != err {
 		return nil, err
 	}
+	return response.meeting(), nil
 }
 
-func loadMeetigsResponse(r *http.Response) []*Meeting {
-	if response, err := loadResponseXML(r); nil == err {
-		if nodes := response.SelectNode("", "meetings").SelectNodes("", "meeting"); len(nodes) > 0 {
-			meetings := make([]*Meeting, len(nodes))
-			for index, meeting := range nodes {
-				meetings[index] = xml2meeting(meeting)
-			}
-			return meetings
+func loadMeetigsResponse(r *http.Response) ([]*Meeting, error) {
+	var response meetingsResponse
+	if err := loadResponse(r, "getMeetings", &response); nil != err {
+		return nil, err
+	}
+	meetings := make([]*Meeting, len(response.Meetings))
+	for index := range response.Meetings {
+		meetings[index] = response.Meetings[index].meeting()
+	}
+	return meetings, nil
+}
+
+func loadRecordingsResponse(r *http.Response) ([]*Recording, error) {
+	var response recordingsResponse
+	if err := loadResponse(r, "getRecordings", &response); nil != err {
+		return nil, err
+	}
+	return response.recordings(), nil
+}
+
+func (r *recordingsResponse) recordings() []*Recording {
+	recordings := make([]*Recording, len(r.Recordings))
+	for index := range r.Recordings {
+		recordings[index] = r.Recordings[index].recording()
+	}
+	return recordings
+}
+
+type meetingXML struct {
+	Id          string        `xml:"meetingID"`
+	InternalId  string        `xml:"internalMeetingID"`
+	Name        string        `xml:"meetingName"`
+	CreateTime  timestamp     `xml:"createTime"`
+	VoiceBridge xmlInt        `xml:"voiceBridge"`
+	AttendeePW  string        `xml:"attendeePW"`
+	ModeratorPW string        `xml:"moderatorPW"`
+	Running     xmlBool       `xml:"running"`
+	Recording   xmlBool       `xml:"recording"`
+	ForcedEnd   xmlBool       `xml:"hasBeenForciblyEnded"`
+	StartTime   timestamp     `xml:"startTime"`
+	EndTime     timestamp     `xml:"endTime"`
+	NumUsers    xmlInt        `xml:"participantCount"`
+	NumMod      xmlInt        `xml:"moderatorCount"`
+	MaxUsers    xmlInt        `xml:"maxUsers"`
+	Attendees   []attendeeXML `xml:"attendees>attendee"`
+	Metadata    xmlMap        `xml:"metadata"`
+
+	IsBreakout    xmlBool     `xml:"isBreakout"`
+	Breakout      breakoutXML `xml:"breakout"`
+	BreakoutRooms []string    `xml:"breakoutRooms>breakout"`
+}
+
+type breakoutXML struct {
+	ParentMeetingID string  `xml:"parentMeetingID"`
+	Sequence        xmlInt  `xml:"sequence"`
+	FreeJoin        xmlBool `xml:"freeJoin"`
+}
+
+type attendeeXML struct {
+	UserId          string  `xml:"userID"`
+	InternalUserId  string  `xml:"internalUserID"`
+	Name            string  `xml:"fullName"`
+	Role            string  `xml:"role"`
+	IsPresenter     xmlBool `xml:"isPresenter"`
+	IsListeningOnly xmlBool `xml:"isListeningOnly"`
+	HasJoinedVoice  xmlBool `xml:"hasJoinedVoice"`
+	HasVideo        xmlBool `xml:"hasVideo"`
+	ClientType      string  `xml:"clientType"`
+	CustomData      xmlMap  `xml:"customdata"`
+	UserData        xmlMap  `xml:"userdata"`
+}
+
+func (m *meetingXML) meeting() *Meeting {
+	attendees := make([]Attendee, len(m.Attendees))
+	for k, a := range m.Attendees {
+		attendees[k] = Attendee{
+			UserId:          a.UserId,
+			InternalUserId:  a.InternalUserId,
+			Name:            a.Name,
+			Role:            a.Role,
+			IsPresenter:     bool(a.IsPresenter),
+			IsListeningOnly: bool(a.IsListeningOnly),
+			HasJoinedVoice:  bool(a.HasJoinedVoice),
+			HasVideo:        bool(a.HasVideo),
+			ClientType:      a.ClientType,
+			CustomData:      a.CustomData.merge(a.UserData),
 		}
 	}
-	return []*Meeting{}
-}
-
-func loadRecordingsResponse(r *http.Response) []*Recording {
-	if response, err := loadResponseXML(r); nil == err {
-		if nodes := response.SelectNode("", "recordings").SelectNodes("", "recording"); len(nodes) > 0 {
-			recordings := make([]*Recording, len(nodes))
-			for index, recording := range nodes {
-				playback := recording.SelectNode("", "playback")
-				recordings[index] = &Recording{
-					RecordId:  recording.S("", "recordId"),
-					MeetingId: recording.S("", "meetingId"),
-					Name:      recording.S("", "name"),
-					StartTime: time.Unix(recording.I64("", "startTime"), 0),
-					EndTime:   time.Unix(recording.I64("", "endTime"), 0),
-					Playback: struct {
-						Type string
-						Url  string
-						Len  int
-					}{
-						playback.S("", "type"),
-						playback.S("", "url"),
-						playback.I("", "length"),
-					},
-				}
+	return &Meeting{
+		Id:          m.Id,
+		InternalId:  m.InternalId,
+		Name:        m.Name,
+		CreateTime:  m.CreateTime.time(),
+		VoiceBridge: int(m.VoiceBridge),
+		AttendeePW:  m.AttendeePW,
+		ModeratorPW: m.ModeratorPW,
+		Running:     bool(m.Running),
+		Recording:   bool(m.Recording),
+		ForcedEnd:   bool(m.ForcedEnd),
+		StartTime:   m.StartTime.time(),
+		EndTime:     m.EndTime.time(),
+		NumUsers:    int(m.NumUsers),
+		NumMod:      int(m.NumMod),
+		MaxUsers:    int(m.MaxUsers),
+		Attendees:   attendees,
+		Metadata:    m.Metadata.merge(nil),
+
+		IsBreakout:      bool(m.IsBreakout),
+		ParentMeetingID: m.Breakout.ParentMeetingID,
+		Sequence:        int(m.Breakout.Sequence),
+		FreeJoin:        bool(m.Breakout.FreeJoin),
+		BreakoutRooms:   m.BreakoutRooms,
+	}
+}
+
+type recordingXML struct {
+	RecordId          string       `xml:"recordID"`
+	RecordIdLegacy    string       `xml:"recordId"`
+	MeetingId         string       `xml:"meetingID"`
+	MeetingIdLegacy   string       `xml:"meetingId"`
+	InternalMeetingId string       `xml:"internalMeetingID"`
+	Name              string       `xml:"name"`
+	Published         xmlBool      `xml:"published"`
+	State             string       `xml:"state"`
+	StartTime         timestamp    `xml:"startTime"`
+	EndTime           timestamp    `xml:"endTime"`
+	Participants      xmlInt       `xml:"participants"`
+	RawSize           xmlInt       `xml:"rawSize"`
+	Size              xmlInt       `xml:"size"`
+	Metadata          xmlMap       `xml:"metadata"`
+	Playback          *playbackXML `xml:"playback"`
+}
+
+type playbackXML struct {
+	Formats []formatXML `xml:"format"`
+
+	// BigBlueButton 0.8 has a single, unwrapped format.
+	formatXML
+}
+
+type formatXML struct {
+	Type           string     `xml:"type"`
+	Url            string     `xml:"url"`
+	ProcessingTime xmlInt     `xml:"processingTime"`
+	Length         xmlInt     `xml:"length"`
+	Size           xmlInt     `xml:"size"`
+	Preview        []imageXML `xml:"preview>images>image"`
+	Images         []imageXML `xml:"images>image"`
+}
+
+type imageXML struct {
+	Url    string `xml:",chardata"`
+	Alt    string `xml:"alt,attr"`
+	Width  xmlInt `xml:"width,attr"`
+	Height xmlInt `xml:"height,attr"`
+}
+
+func (r *recordingXML) recording() *Recording {
+	recording := &Recording{
+		RecordId:          r.RecordId,
+		MeetingId:         r.MeetingId,
+		InternalMeetingId: r.InternalMeetingId,
+		Name:              r.Name,
+		Published:         bool(r.Published),
+		State:             r.State,
+		StartTime:         r.StartTime.time(),
+		EndTime:           r.EndTime.time(),
+		Participants:      int(r.Participants),
+		RawSize:           int64(r.RawSize),
+		Size:              int64(r.Size),
+		Metadata:          r.Metadata.merge(nil),
+	}
+	if "" == recording.RecordId {
+		recording.RecordId = r.RecordIdLegacy
+	}
+	if "" == recording.MeetingId {
+		recording.MeetingId = r.MeetingIdLegacy
+	}
+	if nil != r.Playback {
+		formats := r.Playback.Formats
+		if len(formats) < 1 {
+			formats = []formatXML{r.Playback.formatXML}
+		}
+		for _, format := range formats {
+			p := Playback{
+				Type:           format.Type,
+				Url:            strings.TrimSpace(format.Url),
+				ProcessingTime: time.Duration(format.ProcessingTime) * time.Millisecond,
+				Length:         time.Duration(format.Length) * time.Minute,
+				Size:           int64(format.Size),
+			}
+			for _, image := range append(format.Preview, format.Images...) {
+				p.Preview = append(p.Preview, PreviewImage{
+					Url:    strings.TrimSpace(image.Url),
+					Alt:    image.Alt,
+					Width:  int(image.Width),
+					Height: int(image.Height),
+				})
 			}
-			return recordings
+			recording.Playback = append(recording.Playback, p)
 		}
 	}
-	return []*Recording{}
+	return recording
+}
+
+type hookXML struct {
+	Id          string  `xml:"hookID"`
+	CallbackURL string  `xml:"callbackURL"`
+	MeetingId   string  `xml:"meetingID"`
+	Permanent   xmlBool `xml:"permanentHook"`
+	Raw         xmlBool `xml:"rawData"`
+}
+
+func (h *hookXML) hook() *Hook {
+	return &Hook{
+		Id:          h.Id,
+		CallbackURL: h.CallbackURL,
+		MeetingId:   h.MeetingId,
+		Permanent:   bool(h.Permanent),
+		Raw:         bool(h.Raw),
+	}
+}
+
+// xmlBool and xmlInt decode empty values as false and 0, like the servers,
+// which leave unset values empty in some versions. Malformed values fail.
+type xmlBool bool
+
+func (b *xmlBool) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if "" == s {
+		*b = false
+		return nil
+	}
+	v, err := strconv.ParseBool(s)
+	*b = xmlBool(v)
+	return err
 }
 
-func loadBoolResponse(r *http.Response, element string) bool {
-	if response, err := loadResponseXML(r); nil == err {
-		return response.B("", element)
+type xmlInt int64
+
+func (i *xmlInt) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if "" == s {
+		*i = 0
+		return nil
 	}
-	return false
+	v, err := strconv.ParseInt(s, 10, 64)
+	*i = xmlInt(v)
+	return err
 }
 
-func loadStringResponse(r *http.Response, element string) string {
-	if response, err := loadResponseXML(r); nil == err {
-		return response.S("", element)
-	} else {
-		return err.Error()
+// timestamp is a time in milliseconds since the epoch; BigBlueButton 0.8
+// sends recording times as dates, e.g. "Thu Mar 04 14:05:56 UTC 2010".
+// Empty values and 0 are the zero time.
+type timestamp time.Time
+
+func (t *timestamp) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if "" == s || "0" == s {
+		*t = timestamp{}
+		return nil
 	}
+	if ms, err := strconv.ParseInt(s, 10, 64); nil == err {
+		*t = timestamp(mstime(ms))
+		return nil
+	}
+	date, err := time.Parse(time.UnixDate, s)
+	if nil != err {
+		return fmt.Errorf("invalid timestamp %q", s)
+	}
+	*t = timestamp(date)
+	return nil
+}
+
+func (t timestamp) time() time.Time {
+	return time.Time(t)
 }
 
-func xml2meeting(meeting *xmlx.Node) *Meeting {
-	if nil != meeting {
-		return &Meeting{
-			Id:          meeting.S("", "meetingID"),
-			Name:        meeting.S("", "meetingName"),
-			CreateTime:  mstime(meeting.I64("", "createTime")),
-			AttendeePW:  meeting.S("", "attendeePW"),
-			ModeratorPW: meeting.S("", "moderatorPW"),
-			ForcedEnd:   meeting.B("", "hasBeenForciblyEnded"),
+// MarshalXML encodes the recording as servers list it in getRecordings
+// responses.
+func (r *Recording) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type image struct {
+		Alt    string `xml:"alt,attr"`
+		Height int    `xml:"height,attr"`
+		Width  int    `xml:"width,attr"`
+		Url    string `xml:",chardata"`
+	}
+	type format struct {
+		Type           string  `xml:"type"`
+		Url            string  `xml:"url"`
+		ProcessingTime int64   `xml:"processingTime"`
+		Length         int64   `xml:"length"`
+		Size           int64   `xml:"size,omitempty"`
+		Preview        []image `xml:"preview>images>image,omitempty"`
+	}
+	x := struct {
+		RecordId          string   `xml:"recordID"`
+		MeetingId         string   `xml:"meetingID"`
+		InternalMeetingId string   `xml:"internalMeetingID"`
+		Name              string   `xml:"name"`
+		Published         bool     `xml:"published"`
+		State             string   `xml:"state"`
+		StartTime         int64    `xml:"startTime"`
+		EndTime           int64    `xml:"endTime"`
+		Participants      int      `xml:"participants"`
+		RawSize           int64    `xml:"rawSize"`
+		Metadata          xmlMap   `xml:"metadata"`
+		Size              int64    `xml:"size"`
+		Formats           []format `xml:"playback>format"`
+	}{
+		RecordId:          r.RecordId,
+		MeetingId:         r.MeetingId,
+		InternalMeetingId: r.InternalMeetingId,
+		Name:              r.Name,
+		Published:         r.Published,
+		State:             r.State,
+		StartTime:         unixms(r.StartTime),
+		EndTime:           unixms(r.EndTime),
+		Participants:      r.Participants,
+		RawSize:           r.RawSize,
+		Metadata:          xmlMap(r.Metadata),
+		Size:              r.Size,
+	}
+	for _, p := range r.Playback {
+		f := format{
+			Type:           p.Type,
+			Url:            p.Url,
+			ProcessingTime: int64(p.ProcessingTime / time.Millisecond),
+			Length:         int64(p.Length / time.Minute),
+			Size:           p.Size,
+		}
+		for _, i := range p.Preview {
+			f.Preview = append(f.Preview, image{i.Alt, i.Height, i.Width, i.Url})
 		}
+		x.Formats = append(x.Formats, f)
 	}
-	return &Meeting{}
+	return e.EncodeElement(x, start)
 }
 
-func buildCreateMeetingXML(docs []ConfigXML_Document) ([]byte, error) {
-	doc, config := xmlx.New(), &ConfigXML{
-		Modules: []ConfigXML_Module{
-			{Name: "presentation", Documents: docs},
-		},
+// xmlMap decodes elements with arbitrary children, like <metadata>, as a
+// map of the child names to their text.
+type xmlMap map[string]string
+
+func (m *xmlMap) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if nil == *m {
+		*m = xmlMap{}
 	}
-	if err := doc.LoadString(config.String(), nil); nil != err {
-		return []byte(nil), err
+	for {
+		token, err := d.Token()
+		if nil != err {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			var value struct {
+				Text string `xml:",chardata"`
+			}
+			if err := d.DecodeElement(&value, &t); nil != err {
+				return err
+			}
+			(*m)[t.Name.Local] = value.Text
+		case xml.EndElement:
+			return nil
+		}
 	}
-	return []byte(doc.SelectNode("", "modules").String()), nil
 }
 
-func mstime(ts int64) time.Time {
-	return time.Unix(int64(ts/int64(time.Microsecond)), 0)
+// MarshalXML encodes the map as one child element per key, sorted by key.
+func (m xmlMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); nil != err {
+		return err
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := e.EncodeElement(m[k], xml.StartElement{Name: xml.Name{Local: k}}); nil != err {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
 }
 
-type xmlError string
+// merge returns a copy of m with the entries of other added; the result is
+// never nil.
+func (m xmlMap) merge(other xmlMap) map[string]string {
+	merged := make(map[string]string, len(m)+len(other))
+	for k, v := range m {
+		merged[k] = v
+	}
+	for k, v := range other {
+		merged[k] = v
+	}
+	return merged
+}
 
-func (err xmlError) Error() string {
-	return string(err)
+func mstime(ts int64) time.Time {
+	return time.Unix(ts/1000, ts%1000*int64(time.Millisecond))
+}
+
+// unixms is the inverse of mstime; the zero time is 0.
+func unixms(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano() / int64(time.Millisecond)
 }
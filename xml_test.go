@@ -0,0 +1,231 @@
+package bbb
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func fixture(t *testing.T, name string) *http.Response {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(f)}
+}
+
+func TestFixtures(t *testing.T) {
+	names, err := filepath.Glob(filepath.Join("testdata", "*", "*.xml"))
+	if nil != err || 0 == len(names) {
+		t.Fatalf("no fixtures: %v", err)
+	}
+	for _, name := range names {
+		f, err := os.Open(name)
+		if nil != err {
+			t.Fatal(err)
+		}
+		var response responseXML
+		if err := decodeResponse(f, &response); nil != err {
+			t.Errorf("%s: %v", name, err)
+		} else if "SUCCESS" != response.ReturnCode && "FAILED" != response.ReturnCode {
+			t.Errorf("%s: unexpected returncode %q", name, response.ReturnCode)
+		}
+		f.Close()
+	}
+}
+
+func TestMeetingFixtures(t *testing.T) {
+	m, err := loadMeetingInfoResponse(fixture(t, "0.8/getMeetingInfo.xml"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "Demo Meeting" != m.Id || 70066 != m.VoiceBridge || !m.Running || 20 != m.MaxUsers ||
+		1 != len(m.Attendees) || "John Doe" != m.Attendees[0].Name || "MODERATOR" != m.Attendees[0].Role {
+		t.Errorf("unexpected 0.8 meeting: %#v", m)
+	}
+	if !m.CreateTime.Equal(time.Unix(1312297631, 224*int64(time.Millisecond))) {
+		t.Errorf("expected millisecond precision, got %v", m.CreateTime)
+	}
+	if !m.EndTime.IsZero() || 0 != len(m.Metadata) {
+		t.Errorf("unexpected 0.8 end time or metadata: %v %v", m.EndTime, m.Metadata)
+	}
+
+	m, err = loadMeetingInfoResponse(fixture(t, "0.9/getMeetingInfo.xml"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !m.Recording || 2 != m.NumUsers || 2 != len(m.Attendees) || !m.Attendees[0].IsPresenter ||
+		!m.Attendees[1].IsListeningOnly || "true" != m.Attendees[1].CustomData["bbb_skip_check_audio"] {
+		t.Errorf("unexpected 0.9 meeting: %#v", m)
+	}
+	if "Moodle" != m.Metadata["bn-origin"] {
+		t.Errorf("unexpected 0.9 metadata: %v", m.Metadata)
+	}
+
+	m, err = loadMeetingInfoResponse(fixture(t, "2.x/getMeetingInfo.xml"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	mod := m.Attendees[1]
+	if "w_eo7lxnx3vwuj" != mod.InternalUserId || "HTML5" != mod.ClientType || !mod.HasVideo ||
+		"body{}" != mod.CustomData["bbb_custom_style"] || "false" != mod.CustomData["bbb_show_participants_on_login"] {
+		t.Errorf("unexpected 2.x attendee: %#v", mod)
+	}
+	if "Greenlight" != m.Metadata["bbb-origin"] || m.IsBreakout || 2 != len(m.BreakoutRooms) {
+		t.Errorf("unexpected 2.x meeting: %#v", m)
+	}
+
+	m, err = loadMeetingInfoResponse(fixture(t, "2.x/getMeetingInfo-breakout.xml"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !m.IsBreakout || 1 != m.Sequence || m.FreeJoin || 0 != m.MaxUsers ||
+		"183f0bf3a0982a127bdb8161e0c44cb696b3e75c-1531240585189" != m.ParentMeetingID {
+		t.Errorf("unexpected breakout room: %#v", m)
+	}
+
+	meetings, err := loadMeetigsResponse(fixture(t, "0.8/getMeetings.xml"))
+	if nil != err || 1 != len(meetings) || "mp" != meetings[0].ModeratorPW {
+		t.Errorf("unexpected 0.8 meetings: %v (%v)", meetings, err)
+	}
+	meetings, err = loadMeetigsResponse(fixture(t, "2.x/getMeetings.xml"))
+	if nil != err || 2 != len(meetings) || "cs101" != meetings[1].Metadata["course"] ||
+		1 != len(meetings[1].Attendees) || 0 != len(meetings[0].Attendees) {
+		t.Errorf("unexpected 2.x meetings: %v (%v)", meetings, err)
+	}
+	meetings, err = loadMeetigsResponse(fixture(t, "2.x/getMeetings-empty.xml"))
+	if nil != err || 0 != len(meetings) {
+		t.Errorf("unexpected empty meetings: %v (%v)", meetings, err)
+	}
+
+	m, err = loadMeetingCreateResponse(fixture(t, "2.x/duplicateWarning.xml"))
+	if nil != err || "Test" != m.Id || "mp" != m.ModeratorPW || 70757 != m.VoiceBridge {
+		t.Errorf("unexpected create response: %v (%v)", m, err)
+	}
+}
+
+func TestRecordingFixtures(t *testing.T) {
+	recordings, err := loadRecordingsResponse(fixture(t, "0.8/getRecordings.xml"))
+	if nil != err || 1 != len(recordings) {
+		t.Fatalf("unexpected 0.8 recordings: %v (%v)", recordings, err)
+	}
+	r := recordings[0]
+	if "On-line Learning (CS101)" != r.Name || "Test Recording" != r.Metadata["title"] || !r.Published {
+		t.Errorf("unexpected 0.8 recording: %#v", r)
+	}
+	if !r.StartTime.Equal(time.Date(2010, 3, 4, 14, 5, 56, 0, time.UTC)) {
+		t.Errorf("unexpected 0.8 start time: %v", r.StartTime)
+	}
+	if 1 != len(r.Playback) || "simple" != r.Playback[0].Type || 62*time.Minute != r.Playback[0].Length {
+		t.Errorf("unexpected 0.8 playback: %#v", r.Playback)
+	}
+
+	recordings, err = loadRecordingsResponse(fixture(t, "0.9/getRecordings.xml"))
+	if nil != err || 1 != len(recordings) || 2 != len(recordings[0].Playback) ||
+		nil == recordings[0].Format("slides") || 1308597750 != recordings[0].EndTime.Unix() {
+		t.Errorf("unexpected 0.9 recordings: %v (%v)", recordings, err)
+	}
+
+	recordings, err = loadRecordingsResponse(fixture(t, "2.x/getRecordings.xml"))
+	if nil != err || 1 != len(recordings) {
+		t.Fatalf("unexpected 2.x recordings: %v (%v)", recordings, err)
+	}
+	r = recordings[0]
+	if "Fred's Room" != r.Name || "published" != r.State || 3 != r.Participants ||
+		951067 != r.RawSize || 1104836 != r.Size || "false" != r.Metadata["gl-listed"] {
+		t.Errorf("unexpected 2.x recording: %#v", r)
+	}
+	p := r.Format("presentation")
+	if nil == p || 7177*time.Millisecond != p.ProcessingTime || 2 != len(p.Preview) ||
+		"Welcome to" != p.Preview[0].Alt || 176 != p.Preview[0].Width || 136 != p.Preview[0].Height {
+		t.Errorf("unexpected presentation playback: %#v", p)
+	}
+	if v := r.Format("video"); nil == v || 2104836 != v.Size || 0 != len(v.Preview) {
+		t.Errorf("unexpected video playback: %#v", v)
+	}
+
+	recordings, err = loadRecordingsResponse(fixture(t, "2.x/getRecordings-empty.xml"))
+	if nil != err || 0 != len(recordings) {
+		t.Errorf("unexpected empty recordings: %v (%v)", recordings, err)
+	}
+}
+
+func TestStatusFixtures(t *testing.T) {
+	for name, v := range map[string]interface{}{
+		"2.x/isMeetingRunning.xml":  &runningResponse{},
+		"2.x/publishRecordings.xml": &publishResponse{},
+		"2.x/deleteRecordings.xml":  &deleteResponse{},
+		"2.x/updateRecordings.xml":  &updateResponse{},
+		"2.x/hooks-destroy.xml":     &hookDestroyResponse{},
+	} {
+		if err := loadResponse(fixture(t, name), "", v.(response)); nil != err {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	running, updated := runningResponse{}, updateResponse{}
+	loadResponse(fixture(t, "2.x/isMeetingRunning.xml"), "", &running)
+	loadResponse(fixture(t, "2.x/updateRecordings.xml"), "", &updated)
+	if !running.Running || !updated.Updated {
+		t.Errorf("unexpected flags: %v %v", running.Running, updated.Updated)
+	}
+
+	var token configTokenResponse
+	if err := loadResponse(fixture(t, "2.x/setConfigXML.xml"), "", &token); nil != err || "4a67ddc1d9ab4f4f" != token.ConfigToken {
+		t.Errorf("unexpected config token: %q (%v)", token.ConfigToken, err)
+	}
+	var version versionResponse
+	if err := loadResponse(fixture(t, "2.x/version.xml"), "", &version); nil != err || "2.0" != version.Version {
+		t.Errorf("unexpected version: %q (%v)", version.Version, err)
+	}
+
+	var hook hookResponse
+	if err := loadResponse(fixture(t, "2.x/hooks-create.xml"), "", &hook); nil != err || "1" != hook.hook().Id {
+		t.Errorf("unexpected hook: %#v (%v)", hook, err)
+	}
+	hooks, err := loadHooksResponse(fixture(t, "2.x/hooks-list.xml"), "hooks/list")
+	if nil != err || 2 != len(hooks) || "my-meeting" != hooks[0].MeetingId ||
+		"http://postcatcher.in/catchers/another" != hooks[1].CallbackURL || !hooks[1].Permanent || !hooks[1].Raw {
+		t.Errorf("unexpected hooks: %v (%v)", hooks, err)
+	}
+
+	for name, check := range map[string]func(error) bool{
+		"0.8/invalidMeetingIdentifier.xml": IsNotFound,
+		"2.x/notFound.xml":                 IsNotFound,
+		"2.x/checksumError.xml":            IsChecksumError,
+	} {
+		if _, err := loadMeetingInfoResponse(fixture(t, name)); !check(err) {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+}
+
+func TestMstime(t *testing.T) {
+	if ts := mstime(1531240585189); 1531240585 != ts.Unix() || 189*int64(time.Millisecond) != int64(ts.Nanosecond()) {
+		t.Errorf("unexpected time: %v", ts)
+	}
+	if 0 != mstime(0).Unix() {
+		t.Errorf("unexpected zero time: %v", mstime(0))
+	}
+}
+
+func TestXMLScalars(t *testing.T) {
+	var v struct {
+		B xmlBool   `xml:"b"`
+		I xmlInt    `xml:"i"`
+		T timestamp `xml:"t"`
+	}
+	if err := xml.Unmarshal([]byte(`<r><b></b><i> </i><t>0</t></r>`), &v); nil != err || bool(v.B) || 0 != v.I || !v.T.time().IsZero() {
+		t.Errorf("unexpected empty values: %#v (%v)", v, err)
+	}
+	for _, data := range []string{`<r><b>yes</b></r>`, `<r><i>12x</i></r>`, `<r><t>yesterday</t></r>`} {
+		if err := xml.Unmarshal([]byte(data), &v); nil == err {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}